@@ -2,19 +2,25 @@ package app
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 	appAuth "twitchy-api/internal/app/auth"
+	"twitchy-api/internal/app/authz"
 	authStorage "twitchy-api/internal/auth/storage"
 	categoryService "twitchy-api/internal/category/service"
 	categoryStorage "twitchy-api/internal/category/storage"
+	channelService "twitchy-api/internal/channel/service"
 	channelStorage "twitchy-api/internal/channel/storage"
 	authExternal "twitchy-api/internal/external/auth"
+	"twitchy-api/internal/external/mailer"
+	"twitchy-api/internal/external/payment"
 	"twitchy-api/internal/external/streamserver"
 	"twitchy-api/internal/external/taskqueue"
 	followStorage "twitchy-api/internal/follow/storage"
@@ -22,6 +28,8 @@ import (
 	"twitchy-api/internal/lib/sl"
 	livestreamService "twitchy-api/internal/livestream/service"
 	livestreamStorage "twitchy-api/internal/livestream/storage"
+	subscriptionService "twitchy-api/internal/subscription/service"
+	subscriptionStorage "twitchy-api/internal/subscription/storage"
 	userStorage "twitchy-api/internal/user/storage"
 
 	"github.com/hibiken/asynq"
@@ -74,8 +82,9 @@ func Run() error {
 	eg, ctx := errgroup.WithContext(ctx)
 	app.Init(ctx, cfg.Update, eg)
 
-	authMw := NewAuthMiddleware(log, cfg.AuthMiddlewareMock)
-	handler := app.CreateHandler(authMw)
+	authMw := NewAuthMiddleware(log, cfg.AuthMiddlewareMock, rdb, app.Keys, app.UserRepo)
+	optionalAuthMw := NewOptionalAuthMiddleware(log, cfg.AuthMiddlewareMock, rdb, app.Keys, app.UserRepo)
+	handler := app.CreateHandler(authMw, optionalAuthMw)
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", cfg.HTTP.Host, cfg.HTTP.Port),
 		Handler:      handler,
@@ -90,6 +99,20 @@ func Run() error {
 		}
 	}()
 
+	internalServer := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", cfg.HTTP.InternalHost, cfg.HTTP.InternalPort),
+		Handler:      CreateInternalHandler(),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout}
+
+	go func() {
+		if err := internalServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("internal server down", sl.Err(err))
+			stop()
+		}
+	}()
+
 	<-ctx.Done()
 	log.Info("shutting down...")
 	shutdownCtx, cancelTimeout := context.WithTimeout(ctx, 3*time.Second)
@@ -100,6 +123,10 @@ func Run() error {
 		stop()
 	}
 
+	if err := internalServer.Shutdown(shutdownCtx); err != nil {
+		log.Error("can't shutdown internal server gracefully", sl.Err(err))
+	}
+
 	log.Info("server shut down gracefully")
 
 	return err
@@ -107,15 +134,26 @@ func Run() error {
 
 type App struct {
 	log                 *slog.Logger
+	rdb                 *redis.Client
+	instanceID          string
+	webhookSecret       string
+	Keys                *appAuth.Keys
+	Mailer              mailer.Mailer
+	PaymentProvider     payment.Provider
 	AuthService         *authStorage.ServiceImpl
 	StreamServerAdapter *streamserver.Adapter
 	LivestreamRepo      *livestreamStorage.RepositoryImpl
 	LivestreamUpdater   *livestreamService.Updater
+	ViewersFlusher      *livestreamService.ViewersFlusher
+	Reconciler          *livestreamService.Reconciler
 	CategoryRepo        *categoryStorage.RepositoryImpl
 	CategoryUpdater     *categoryService.CategoryUpdater
 	FollowRepo          *followStorage.RepositoryImpl
 	UserRepo            *userStorage.RepositoryImpl
 	ChannelRepo         *channelStorage.RepositoryImpl
+	BanCleaner          *channelService.BanCleaner
+	SubscriptionService *subscriptionStorage.ServiceImpl
+	SubscriptionRenewer *subscriptionService.Renewer
 	TaskQServer         *asynq.Server
 	TaskScheduler       *taskqueue.Scheduler
 }
@@ -127,24 +165,46 @@ func NewApp(log *slog.Logger,
 	cfg Config) (*App, error) {
 	livestreamRepo := livestreamStorage.NewRepo(rdb, pool)
 
-	channelRepo := channelStorage.NewRepository(pool)
+	followRepo := followStorage.NewRepository(pool, rdb, livestreamRepo)
+
+	channelRepo := channelStorage.NewRepository(pool, followRepo)
 
 	categoryRepo := categoryStorage.NewRepo(rdb, pool)
 	categoryUpdater := categoryService.NewUpdater(log, livestreamRepo, categoryRepo)
 
-	authClient, err := NewAuthClient(log, cfg.Env, cfg.AuthServiceMock, cfg.GRPC)
+	keys, err := NewKeys(log, cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize jwt keys: %v", err)
+	}
+
+	authClient, err := NewAuthClient(log, cfg.Env, cfg.AuthServiceMock, cfg.GRPC, keys)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize auth client: %v", err)
 	}
-	authService := authStorage.NewService(authClient, pool)
+	ml := NewMailer(log, cfg.Mail)
+	authService := authStorage.NewService(log,
+		authClient,
+		pool,
+		rdb,
+		keys,
+		ml,
+		authStorage.MailLinks{Verify: cfg.Mail.VerifyURL, Reset: cfg.Mail.ResetURL})
+
+	pp, err := NewPaymentProvider(log, cfg.Payment)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize payment provider: %v", err)
+	}
+	subscriptions := subscriptionStorage.NewService(log, pool, pp)
 
-	followRepo := followStorage.NewRepository(pool)
+	if cfg.Webhook.Secret == "" {
+		log.Warn("WEBHOOK_SECRET is empty, stream server webhooks will be rejected")
+	}
 
 	userRepo := userStorage.NewRepository(pool)
 
 	ssURL := fmt.Sprintf("http://%s:%s%s/",
 		cfg.StreamServer.Host, cfg.StreamServer.Port, cfg.StreamServer.Endpoint)
-	streamServerAdapter := streamserver.NewAdapter(ssURL)
+	streamServerAdapter := streamserver.NewAdapter(ssURL, cfg.StreamServer.SnapshotURL)
 
 	asyncRedis := fmt.Sprintf("%s:%s", cfg.Asynq.RedisHost, cfg.Asynq.RedisPort)
 	taskqserv := asynq.NewServer(asynq.RedisClientOpt{Addr: asyncRedis},
@@ -159,12 +219,37 @@ func NewApp(log *slog.Logger,
 		sched,
 		cfg.InstanceID.String())
 
+	viewersFlusher := livestreamService.NewViewersFlusher(log,
+		rdb,
+		livestreamRepo,
+		categoryRepo)
+
+	banCleaner := channelService.NewBanCleaner(log, channelRepo, sched)
+	subscriptionRenewer := subscriptionService.NewRenewer(log, subscriptions, sched)
+
+	reconciler := livestreamService.NewReconciler(log,
+		rdb,
+		streamServerAdapter,
+		livestreamRepo,
+		livestreamUpdater)
+
 	return &App{
 		log:                 log,
+		Reconciler:          reconciler,
+		rdb:                 rdb,
+		instanceID:          cfg.InstanceID.String(),
+		webhookSecret:       cfg.Webhook.Secret,
+		Keys:                keys,
+		Mailer:              ml,
+		PaymentProvider:     pp,
+		ViewersFlusher:      viewersFlusher,
 		AuthService:         authService,
 		LivestreamRepo:      livestreamRepo,
 		LivestreamUpdater:   livestreamUpdater,
 		ChannelRepo:         channelRepo,
+		BanCleaner:          banCleaner,
+		SubscriptionService: subscriptions,
+		SubscriptionRenewer: subscriptionRenewer,
 		CategoryRepo:        categoryRepo,
 		CategoryUpdater:     categoryUpdater,
 		FollowRepo:          followRepo,
@@ -175,9 +260,25 @@ func NewApp(log *slog.Logger,
 }
 
 func (a *App) Init(ctx context.Context, cfg UpdateConfig, eg *errgroup.Group) {
+	// updaters resume from the cache, so it has to be filled first
+	a.warmUpCaches(ctx)
+
 	asyncqMux := asynq.NewServeMux()
 	asyncqMux.HandleFunc(livestreamService.TaskUpdate,
 		taskqueue.TaskHandler(a.LivestreamUpdater.HandleUpdateTask))
+	asyncqMux.HandleFunc(channelService.TaskBanCleanup,
+		taskqueue.TaskHandler(a.BanCleaner.HandleCleanupTask))
+
+	asyncqMux.HandleFunc(subscriptionService.TaskRenew,
+		taskqueue.TaskHandler(a.SubscriptionRenewer.HandleRenewTask))
+
+	if err := a.BanCleaner.Schedule(cfg.BanCleanupTimeout); err != nil {
+		a.log.Error("unable to schedule ban cleanup", sl.Err(err))
+	}
+
+	if err := a.SubscriptionRenewer.Schedule(cfg.SubscriptionRenewTimeout); err != nil {
+		a.log.Error("unable to schedule subscription renewal", sl.Err(err))
+	}
 
 	eg.Go(func() error {
 		err := a.TaskQServer.Run(asyncqMux)
@@ -204,37 +305,98 @@ func (a *App) Init(ctx context.Context, cfg UpdateConfig, eg *errgroup.Group) {
 	eg.Go(func() error {
 		return a.LivestreamUpdater.Run(ctx, cfg.LivestreamsTimeout)
 	})
+
+	eg.Go(func() error {
+		return a.ViewersFlusher.Run(ctx, cfg.ViewersFlushTimeout)
+	})
+
+	eg.Go(func() error {
+		return a.Reconciler.Run(ctx, cfg.ReconcileTimeout)
+	})
+
+	eg.Go(func() error {
+		return a.Keys.Run(ctx)
+	})
 }
 
-func (a *App) CreateHandler(authMw mware) http.Handler {
+func (a *App) CreateHandler(authMw, optionalAuthMw mware) http.Handler {
 	mux := http.NewServeMux()
 
 	addRoutes(mux, a.log,
 		authMw,
+		optionalAuthMw,
+		authz.New(a.log, a.ChannelRepo),
+		a.Keys,
 		a.CategoryRepo,
 		a.LivestreamRepo,
+		a.LivestreamUpdater,
 		a.ChannelRepo,
 		a.AuthService,
 		a.FollowRepo,
-		a.UserRepo)
+		a.UserRepo,
+		a.SubscriptionService,
+		a.webhookSecret)
 
 	panicRecovery := mw.PanicRecovery(a.log)
 	logging := mw.Logging(a.log)
 	return mw.RequestID(panicRecovery(mw.JSONResponse(mw.CORS(logging(mux)))))
 }
 
+// runtime and reconciler metrics, served on a separate listener that is not exposed publicly
+func CreateInternalHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+
+	return mux
+}
+
 type mware = func(next http.HandlerFunc) http.HandlerFunc
 
-func NewAuthMiddleware(log *slog.Logger, isMock bool) mware {
+func NewAuthMiddleware(log *slog.Logger, isMock bool, rdb *redis.Client, keys *appAuth.Keys, users appAuth.UserIds) mware {
 	if isMock {
 		log.Info("Initializating mock auth middleware because AUTH_MIDDLEWARE_MOCK == true")
 		return appAuth.AuthMiddlewareMock(log)
 	} else {
-		return appAuth.AuthMiddleware(log)
+		return appAuth.AuthMiddleware(log, keys, appAuth.NewDenylist(rdb), users)
+	}
+}
+
+// same as NewAuthMiddleware, but lets anonymous requests through
+func NewOptionalAuthMiddleware(log *slog.Logger, isMock bool, rdb *redis.Client, keys *appAuth.Keys, users appAuth.UserIds) mware {
+	if isMock {
+		return appAuth.AuthMiddlewareMock(log)
+	} else {
+		return appAuth.OptionalAuthMiddleware(log, keys, appAuth.NewDenylist(rdb), users)
 	}
 }
 
-func NewAuthClient(log *slog.Logger, env string, isMock bool, cfg GrpcClientConfig) (authStorage.Client, error) {
+func NewKeys(log *slog.Logger, cfg JWTConfig) (*appAuth.Keys, error) {
+	secret := cfg.Secret
+	if cfg.SecretFile != "" {
+		data, err := os.ReadFile(cfg.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+
+		secret = strings.TrimSpace(string(data))
+	}
+
+	return appAuth.NewKeys(log, appAuth.KeysConfig{
+		Secret:       secret,
+		Dir:          cfg.KeysDir,
+		JWKSURL:      cfg.JWKSURL,
+		JWKSRefresh:  cfg.JWKSRefresh,
+		Algorithms:   cfg.Algorithms,
+		Issuer:       cfg.Issuer,
+		Audience:     cfg.Audience,
+		SigningKeyID: cfg.SigningKeyID})
+}
+
+func NewAuthClient(log *slog.Logger,
+	env string,
+	isMock bool,
+	cfg GrpcClientConfig,
+	keys *appAuth.Keys) (authStorage.Client, error) {
 	if env == envProd {
 		return authExternal.NewClient(log,
 			cfg.Host,
@@ -253,7 +415,25 @@ func NewAuthClient(log *slog.Logger, env string, isMock bool, cfg GrpcClientConf
 	}
 
 	log.Info("ENV is not prod and AUTH_SERVICE_MOCK == true. Initializing mock grpc auth client.")
-	return &authExternal.ClientMock{}, nil
+	return authExternal.NewClientMock(keys), nil
+}
+
+func NewMailer(log *slog.Logger, cfg MailConfig) mailer.Mailer {
+	if cfg.Driver == "smtp" {
+		return mailer.NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	}
+
+	log.Info("MAIL_DRIVER is not smtp. Emails are logged instead of being sent.")
+	return mailer.NewSink(log, cfg.SinkFile)
+}
+
+func NewPaymentProvider(log *slog.Logger, cfg PaymentConfig) (payment.Provider, error) {
+	if cfg.Driver != "fake" {
+		return nil, fmt.Errorf("unsupported payment driver %q", cfg.Driver)
+	}
+
+	log.Info("PAYMENT_DRIVER is fake. Subscriptions are not charged.")
+	return payment.NewFake(), nil
 }
 
 func NewLogger(cfg LoggerConfig) *slog.Logger {
@@ -8,12 +8,20 @@ type Claims struct {
 	Id       int32  `json:"id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// TypeAccess or TypeRefresh, tokens of one type are not accepted in place of the other
+	Type string `json:"typ"`
 	jwt.RegisteredClaims
 }
 
-var JWTKey = []byte("my_secret_key")
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+	// tokens of the auth service, which doesn't set typ yet
+	TypeLegacy = ""
+)
 
 const (
 	RoleStaff = "staff"
 	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
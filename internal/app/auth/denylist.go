@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// revoked tokens. single tokens are denied by their jti until they expire,
+// signing out everywhere denies every token of the user issued up to that moment
+//
+// keys are "revoked_tokens:<jti>" and "revoked_before:<username>" (unix time in milliseconds)
+type Denylist struct {
+	rdb *redis.Client
+}
+
+func NewDenylist(rdb *redis.Client) *Denylist {
+	return &Denylist{rdb: rdb}
+}
+
+// denies the token until it expires. maxTTL is used for tokens without expiration
+func (dl *Denylist) Revoke(ctx context.Context, claims *Claims, token string, maxTTL time.Duration) error {
+	ttl := maxTTL
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+
+	if ttl <= 0 {
+		return nil
+	}
+
+	return dl.rdb.Set(ctx, dl.key(TokenID(claims, token)), 1, ttl).Err()
+}
+
+// denies every token of the user issued before now.
+// ttl should be not less than lifetime of the longest living token
+func (dl *Denylist) RevokeAll(ctx context.Context, username string, ttl time.Duration) error {
+	return dl.rdb.Set(ctx, dl.userKey(username), time.Now().UnixMilli(), ttl).Err()
+}
+
+func (dl *Denylist) IsRevoked(ctx context.Context, claims *Claims, token string) (bool, error) {
+	var exists *redis.IntCmd
+	var before *redis.StringCmd
+
+	_, err := dl.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		exists = p.Exists(ctx, dl.key(TokenID(claims, token)))
+		before = p.Get(ctx, dl.userKey(claims.Username))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if exists.Val() > 0 {
+		return true, nil
+	}
+
+	revokedBefore, err := before.Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// tokens without iat can't be told apart and are denied
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+
+	// both are compared in milliseconds whatever precision iat is issued with.
+	// whole second iat is rounded down, so tokens of such issuers are denied until the next second
+	issuedAt := claims.IssuedAt.Truncate(time.Millisecond).UnixMilli()
+
+	return issuedAt <= revokedBefore, nil
+}
+
+func (dl *Denylist) key(id string) string {
+	return fmt.Sprintf("revoked_tokens:%s", id)
+}
+
+func (dl *Denylist) userKey(username string) string {
+	return fmt.Sprintf("revoked_before:%s", username)
+}
+
+// jti of the token or hash of the token itself if it has none
+func TokenID(claims *Claims, token string) string {
+	if claims.ID != "" {
+		return claims.ID
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
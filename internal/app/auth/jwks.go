@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+)
+
+// https://www.rfc-editor.org/rfc/rfc7517, only rsa and ed25519 signing keys are supported
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	// rsa
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// okp
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func parseJWKS(r io.Reader) (map[string]key, error) {
+	var doc JWKS
+	err := json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]key, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		// keys without kid can't be told apart from the hmac secret
+		if jwk.Kid == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		k, err := jwk.key()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", jwk.Kid, err)
+		}
+
+		if jwk.Alg != "" && jwk.Alg != k.alg {
+			return nil, fmt.Errorf("jwk %q: %w: %s", jwk.Kid, ErrKeyAlg, jwk.Alg)
+		}
+
+		keys[jwk.Kid] = k
+	}
+
+	return keys, nil
+}
+
+func (jwk JWK) key() (key, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return key{}, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return key{}, err
+		}
+
+		return asymmetricKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return key{}, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return key{}, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return key{}, fmt.Errorf("bad ed25519 key size %d", len(x))
+		}
+
+		return asymmetricKey(ed25519.PublicKey(x))
+	default:
+		return key{}, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// public parts of local asymmetric keys. hmac secrets are never published
+func (k *Keys) JWKS() JWKS {
+	doc := JWKS{Keys: []JWK{}}
+
+	for kid, found := range k.local {
+		jwk := JWK{Kid: kid, Alg: found.alg, Use: "sig"}
+
+		switch pk := found.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pk.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pk.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pk)
+		default:
+			continue
+		}
+
+		doc.Keys = append(doc.Keys, jwk)
+	}
+
+	return doc
+}
+
+// serves JWKS, so tokens signed by the mock auth client can be verified elsewhere
+func (k *Keys) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(k.JWKS())
+}
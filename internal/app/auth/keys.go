@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"twitchy-api/internal/lib/sl"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type KeysConfig struct {
+	// hmac secret for tokens without kid
+	Secret string
+	// directory with keys, file name without extension is kid.
+	// .pem files are rsa or ed25519 keys, .key and .hmac files are hmac secrets, other files are an error
+	Dir string
+	// jwks document of the auth service, refetched every JWKSRefresh and on unknown kid
+	JWKSURL     string
+	JWKSRefresh time.Duration
+	// accepted signing algorithms, every key is bound to a single algorithm on top of that
+	Algorithms []string
+	// validated if not empty
+	Issuer   string
+	Audience string
+	// kid of the private key tokens are signed with by the mock auth client, empty means Secret
+	SigningKeyID string
+}
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrKeyAlg     = errors.New("signing algorithm doesn't match the key")
+	ErrNoKeys     = errors.New("no verification keys: set a secret, a keys directory or a jwks url")
+	ErrKeyFile    = errors.New("unsupported key file, expected .pem, .key or .hmac")
+)
+
+type key struct {
+	alg string
+	// []byte, *rsa.PublicKey or ed25519.PublicKey
+	verify any
+	// nil for keys without private part
+	sign crypto.Signer
+}
+
+// verification keys of tokens. local keys are loaded once, keys from jwks are replaced on every fetch,
+// so rotating a key is publishing the new kid before signing with it and removing the old one after
+// its tokens expire
+type Keys struct {
+	log    *slog.Logger
+	cfg    KeysConfig
+	client *http.Client
+
+	local map[string]key
+
+	mu         sync.RWMutex
+	remote     map[string]key
+	lastFetch  time.Time
+	fetchMu    sync.Mutex
+	parserOpts []jwt.ParserOption
+}
+
+// unknown kid triggers a fetch not more often than this
+const jwksMinRefetch = 10 * time.Second
+
+func NewKeys(log *slog.Logger, cfg KeysConfig) (*Keys, error) {
+	if len(cfg.Algorithms) == 0 {
+		return nil, errors.New("no signing algorithms allowed")
+	}
+
+	for _, alg := range cfg.Algorithms {
+		if jwt.GetSigningMethod(alg) == nil || alg == jwt.SigningMethodNone.Alg() {
+			return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+		}
+	}
+
+	local := make(map[string]key)
+	if cfg.Secret != "" {
+		local[""] = key{alg: jwt.SigningMethodHS256.Alg(), verify: []byte(cfg.Secret)}
+	}
+
+	if cfg.Dir != "" {
+		err := loadDir(cfg.Dir, local)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// every token would be rejected
+	if len(local) == 0 && cfg.JWKSURL == "" {
+		return nil, ErrNoKeys
+	}
+
+	if cfg.SigningKeyID != "" {
+		k, ok := local[cfg.SigningKeyID]
+		if !ok || (k.sign == nil && k.alg != jwt.SigningMethodHS256.Alg()) {
+			return nil, fmt.Errorf("no private key with kid %q", cfg.SigningKeyID)
+		}
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(cfg.Algorithms)}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &Keys{
+		log:        log,
+		cfg:        cfg,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		local:      local,
+		remote:     make(map[string]key),
+		parserOpts: opts}, nil
+}
+
+// verifies the token and fills claims. opts are applied on top of algorithm, issuer and audience checks
+func (k *Keys) Parse(ctx context.Context, token string, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	opts = append(slices.Clone(k.parserOpts), opts...)
+
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		found, err := k.get(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if found.alg != t.Method.Alg() {
+			return nil, fmt.Errorf("%w: key %q is %s, token is %s", ErrKeyAlg, kid, found.alg, t.Method.Alg())
+		}
+
+		return found.verify, nil
+	}, opts...)
+
+	return err
+}
+
+// signs claims with the configured signing key, used by the mock auth client.
+// issuer and audience are set from the config
+func (k *Keys) Sign(claims *Claims) (string, error) {
+	found, ok := k.local[k.cfg.SigningKeyID]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	if k.cfg.Issuer != "" {
+		claims.Issuer = k.cfg.Issuer
+	}
+	if k.cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{k.cfg.Audience}
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(found.alg), claims)
+	if k.cfg.SigningKeyID != "" {
+		token.Header["kid"] = k.cfg.SigningKeyID
+	}
+
+	if found.sign != nil {
+		return token.SignedString(found.sign)
+	}
+
+	return token.SignedString(found.verify)
+}
+
+// periodically refetches jwks, does nothing without JWKSURL
+func (k *Keys) Run(ctx context.Context) error {
+	const op = "auth.Keys.Run"
+
+	if k.cfg.JWKSURL == "" {
+		return nil
+	}
+
+	err := k.fetch(ctx)
+	if err != nil {
+		k.log.Error("fetching jwks", sl.Err(err), sl.Op(op))
+	}
+
+	ticker := time.NewTicker(k.cfg.JWKSRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := k.fetch(ctx)
+			if err != nil {
+				k.log.Error("fetching jwks", sl.Err(err), sl.Op(op))
+			}
+		}
+	}
+}
+
+func (k *Keys) get(ctx context.Context, kid string) (key, error) {
+	if found, ok := k.lookup(kid); ok {
+		return found, nil
+	}
+
+	// token might be signed with a key that was published after the last fetch
+	if k.cfg.JWKSURL == "" || kid == "" {
+		return key{}, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	k.fetchMu.Lock()
+	k.mu.RLock()
+	recent := time.Since(k.lastFetch) < jwksMinRefetch
+	k.mu.RUnlock()
+
+	if !recent {
+		err := k.fetch(ctx)
+		if err != nil {
+			k.log.Error("fetching jwks", sl.Err(err))
+		}
+	}
+	k.fetchMu.Unlock()
+
+	if found, ok := k.lookup(kid); ok {
+		return found, nil
+	}
+
+	return key{}, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+func (k *Keys) lookup(kid string) (key, bool) {
+	if found, ok := k.local[kid]; ok {
+		return found, true
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	found, ok := k.remote[kid]
+	return found, ok
+}
+
+func (k *Keys) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.cfg.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks status %d", resp.StatusCode)
+	}
+
+	remote, err := parseJWKS(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.remote = remote
+	k.lastFetch = time.Now()
+	k.mu.Unlock()
+
+	k.log.Debug("jwks fetched", slog.Int("keys", len(remote)))
+
+	return nil
+}
+
+func loadDir(dir string, keys map[string]key) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+
+		ext := filepath.Ext(e.Name())
+		kid := strings.TrimSuffix(e.Name(), ext)
+
+		var k key
+		switch ext {
+		case ".pem":
+			k, err = parsePEM(data)
+		case ".key", ".hmac":
+			k, err = parseSecret(data)
+		default:
+			return fmt.Errorf("key file %q: %w", e.Name(), ErrKeyFile)
+		}
+		if err != nil {
+			return fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		keys[kid] = k
+	}
+
+	return nil
+}
+
+func parseSecret(data []byte) (key, error) {
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return key{}, errors.New("empty secret")
+	}
+
+	return key{alg: jwt.SigningMethodHS256.Alg(), verify: []byte(secret)}, nil
+}
+
+func parsePEM(data []byte) (key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return key{}, errors.New("no pem block")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return key{}, fmt.Errorf("unsupported pem block %q", block.Type)
+	}
+	if err != nil {
+		return key{}, err
+	}
+
+	return asymmetricKey(parsed)
+}
+
+func asymmetricKey(parsed any) (key, error) {
+	switch pk := parsed.(type) {
+	case *rsa.PublicKey:
+		return key{alg: jwt.SigningMethodRS256.Alg(), verify: pk}, nil
+	case *rsa.PrivateKey:
+		return key{alg: jwt.SigningMethodRS256.Alg(), verify: &pk.PublicKey, sign: pk}, nil
+	case ed25519.PublicKey:
+		return key{alg: jwt.SigningMethodEdDSA.Alg(), verify: pk}, nil
+	case ed25519.PrivateKey:
+		return key{alg: jwt.SigningMethodEdDSA.Alg(), verify: pk.Public(), sign: pk}, nil
+	default:
+		return key{}, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
@@ -2,12 +2,14 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 	"twitchy-api/internal/lib/handler"
+	"twitchy-api/internal/lib/sl"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -18,26 +20,115 @@ func FromContext(ctx context.Context) (*Claims, bool) {
 	return casted, ok
 }
 
-func AuthMiddleware(log *slog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+var (
+	errNoToken      = errors.New("missing Authorization header")
+	errInvalidToken = errors.New("invalid or expired token")
+	errRevokedToken = errors.New("token is revoked")
+	errTokenType    = errors.New("not an access token")
+)
+
+// resolves ids of users whose tokens were issued without one
+type UserIds interface {
+	// false if there is no such user
+	IdByUsername(ctx context.Context, username string) (int32, bool, error)
+}
+
+// verifies the bearer token of the request, only access tokens are accepted. errors other than errNoToken,
+// errInvalidToken and errRevokedToken mean the token couldn't be checked
+func authenticate(r *http.Request, keys *Keys, dl *Denylist, users UserIds) (*Claims, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errNoToken
+	}
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	claims := &Claims{}
+	err := keys.Parse(r.Context(), tokenString, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+
+	// the auth service doesn't set typ yet, tokens without it are taken as access tokens
+	if claims.Type != TypeAccess && claims.Type != TypeLegacy {
+		return nil, fmt.Errorf("%w: %w %q", errInvalidToken, errTokenType, claims.Type)
+	}
+
+	revoked, err := dl.IsRevoked(r.Context(), claims, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, fmt.Errorf("%w: token of %s", errRevokedToken, claims.Username)
+	}
+
+	// the auth service doesn't put the id into tokens yet, handlers rely on it
+	if claims.Id == 0 {
+		id, ok, err := users.IdByUsername(r.Context(), claims.Username)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%w: user %s doesn't exist", errInvalidToken, claims.Username)
+		}
+
+		claims.Id = id
+	}
+
+	return claims, nil
+}
+
+func AuthMiddleware(log *slog.Logger, keys *Keys, dl *Denylist, users UserIds) func(http.HandlerFunc) http.HandlerFunc {
 	const op = "auth middleware"
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				handler.Error(log, w, op, fmt.Errorf("missing Authorization header"),
-					http.StatusUnauthorized, "missing Authorization header")
+			claims, err := authenticate(r, keys, dl, users)
+			if err != nil {
+				if errors.Is(err, errNoToken) {
+					handler.Error(log, w, op, err, http.StatusUnauthorized, errNoToken.Error())
+					return
+				}
+
+				if errors.Is(err, errInvalidToken) {
+					handler.Error(log, w, op, err, http.StatusUnauthorized, errInvalidToken.Error())
+					return
+				}
+
+				if errors.Is(err, errRevokedToken) {
+					handler.Error(log, w, op, err, http.StatusUnauthorized, errRevokedToken.Error())
+					return
+				}
+
+				handler.Error(log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
 				return
 			}
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				return JWTKey, nil
-			})
+			ctx := context.WithValue(r.Context(), AuthContextKey{}, claims)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// variant of AuthMiddleware for public endpoints that personalize their response. requests without a token
+// pass through anonymously, and so do requests with an invalid, expired or revoked one instead of failing
+func OptionalAuthMiddleware(log *slog.Logger, keys *Keys, dl *Denylist, users UserIds) func(http.HandlerFunc) http.HandlerFunc {
+	const op = "optional auth middleware"
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticate(r, keys, dl, users)
+			if err != nil {
+				if errors.Is(err, errInvalidToken) || errors.Is(err, errRevokedToken) {
+					log.Debug("ignoring token", sl.Err(err), sl.Op(op))
+				} else if !errors.Is(err, errNoToken) {
+					log.Error("checking token", sl.Err(err), sl.Op(op))
+				}
 
-			if err != nil || !token.Valid {
-				handler.Error(log, w, op, err, http.StatusUnauthorized, "invalid or expired token")
+				next.ServeHTTP(w, r)
 				return
 			}
 
@@ -56,6 +147,7 @@ func AuthMiddlewareMock(log *slog.Logger) func(http.HandlerFunc) http.HandlerFun
 				Id:       0,
 				Username: "admin",
 				Role:     "staff",
+				Type:     TypeAccess,
 				RegisteredClaims: jwt.RegisteredClaims{
 					Subject:   "test-user-id",
 					ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
@@ -0,0 +1,225 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"twitchy-api/internal/app/auth"
+	"twitchy-api/internal/external/db"
+	"twitchy-api/internal/lib/handler"
+)
+
+type Permission string
+
+const (
+	CategoriesManage Permission = "categories:manage"
+	UsersList        Permission = "users:list"
+	UsersEdit        Permission = "users:edit"
+	UsersDelete      Permission = "users:delete"
+	// setting ban and partner flags of any user, own profile included
+	UsersManage  Permission = "users:manage"
+	ChannelsEdit Permission = "channels:edit"
+	// bans, timeouts and other actions of channel moderators
+	ChannelsModerate Permission = "channels:moderate"
+	// appointing and removing channel moderators
+	ModeratorsManage Permission = "moderators:manage"
+	// creating and deleting subscription tiers of the channel
+	SubscriptionsManage Permission = "subscriptions:manage"
+)
+
+// permissions granted by app_role_enum regardless of the resource
+var rolePermissions = map[db.AppRoleEnum][]Permission{
+	db.AppRoleEnumUser: {},
+	db.AppRoleEnumStaff: {
+		CategoriesManage,
+		UsersList,
+		UsersEdit,
+		UsersDelete,
+		UsersManage,
+		ChannelsEdit,
+		ChannelsModerate,
+	},
+	db.AppRoleEnumAdmin: {
+		CategoriesManage,
+		UsersList,
+		UsersEdit,
+		UsersDelete,
+		UsersManage,
+		ChannelsEdit,
+		ChannelsModerate,
+		ModeratorsManage,
+		SubscriptionsManage,
+	},
+}
+
+// permissions granted by relation to the resource
+var (
+	// the user is the resource (own profile) or owns the channel
+	ownerPermissions = []Permission{UsersEdit, UsersDelete, ChannelsEdit, ChannelsModerate, ModeratorsManage, SubscriptionsManage}
+	// the user moderates the channel. moderators can't appoint other moderators
+	moderatorPermissions = []Permission{ChannelsEdit, ChannelsModerate}
+)
+
+var (
+	ErrForbidden = errors.New("forbidden")
+)
+
+// what the permission is checked against
+type Target struct {
+	// user the request is about, 0 if none
+	UserID int32
+	// channel the request is about, empty if none
+	Channel string
+}
+
+// relation of the authenticated user to the target of the request
+type Access struct {
+	Target
+	// the user is the target user or owns the target channel
+	Owner bool
+	// the user moderates the target channel
+	Moderator bool
+}
+
+func (acc Access) Can(claims *auth.Claims, perm Permission) bool {
+	if has(rolePermissions[db.AppRoleEnum(claims.Role)], perm) {
+		return true
+	}
+
+	if acc.Owner && has(ownerPermissions, perm) {
+		return true
+	}
+
+	return acc.Moderator && has(moderatorPermissions, perm)
+}
+
+type AccessContextKey struct{}
+
+// access resolved by Require, so handlers can tell owners and moderators apart without querying again
+func FromContext(ctx context.Context) (Access, bool) {
+	access, ok := ctx.Value(AccessContextKey{}).(Access)
+	return access, ok
+}
+
+// extracts target from the request
+type Resource func(r *http.Request) (Target, error)
+
+type ModeratorChecker interface {
+	IsModerator(ctx context.Context, channel string, userID int32) (bool, error)
+}
+
+type Authorizer struct {
+	log  *slog.Logger
+	mods ModeratorChecker
+}
+
+func New(log *slog.Logger, mods ModeratorChecker) *Authorizer {
+	return &Authorizer{log: log, mods: mods}
+}
+
+// allows the request if the user has the permission on the resource. nil resource checks role only.
+// claims are expected to be put into the context by auth middleware before
+func (a *Authorizer) Require(perm Permission, res Resource) func(http.HandlerFunc) http.HandlerFunc {
+	op := fmt.Sprintf("authorizing %s", perm)
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := auth.FromContext(r.Context())
+			if !ok {
+				handler.Error(a.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+				return
+			}
+
+			var target Target
+			if res != nil {
+				var err error
+				target, err = res(r)
+				if err != nil {
+					handler.Error(a.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+					return
+				}
+			}
+
+			access, err := a.Access(r.Context(), claims, target)
+			if err != nil {
+				handler.Error(a.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+				return
+			}
+
+			if !access.Can(claims, perm) {
+				handler.Error(a.log, w, op, fmt.Errorf("%w: %s has no %s", ErrForbidden, claims.Username, perm),
+					http.StatusForbidden, handler.ErrNotAllowed.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), AccessContextKey{}, access)))
+		})
+	}
+}
+
+func (a *Authorizer) Can(ctx context.Context, claims *auth.Claims, perm Permission, target Target) (bool, error) {
+	access, err := a.Access(ctx, claims, target)
+	if err != nil {
+		return false, err
+	}
+
+	return access.Can(claims, perm), nil
+}
+
+// resolves relation of the user to the target. moderator status is looked up only for channel
+// targets the user doesn't own
+func (a *Authorizer) Access(ctx context.Context, claims *auth.Claims, target Target) (Access, error) {
+	res := Access{
+		Target: target,
+		Owner: (target.UserID != 0 && target.UserID == claims.Id) ||
+			(target.Channel != "" && target.Channel == claims.Username),
+	}
+
+	if target.Channel != "" && !res.Owner && a.mods != nil {
+		isMod, err := a.mods.IsModerator(ctx, target.Channel, claims.Id)
+		if err != nil {
+			return Access{}, err
+		}
+
+		res.Moderator = isMod
+	}
+
+	return res, nil
+}
+
+// user id from the path value
+func PathUser(name string) Resource {
+	return func(r *http.Request) (Target, error) {
+		id, err := strconv.Atoi(r.PathValue(name))
+		if err != nil {
+			return Target{}, err
+		}
+
+		return Target{UserID: int32(id)}, nil
+	}
+}
+
+// channel name from the path value
+func PathChannel(name string) Resource {
+	return func(r *http.Request) (Target, error) {
+		channel := r.PathValue(name)
+		if channel == "" {
+			return Target{}, fmt.Errorf("no %s in path", name)
+		}
+
+		return Target{Channel: channel}, nil
+	}
+}
+
+func has(perms []Permission, perm Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+
+	return false
+}
@@ -25,6 +25,10 @@ type Config struct {
 	Asynq              AsynqConfig
 	Update             UpdateConfig
 	StreamServer       StreamServerConfig
+	JWT                JWTConfig
+	Mail               MailConfig
+	Payment            PaymentConfig
+	Webhook            WebhookConfig
 	Env                string `env:"ENV" env-default:"prod"`
 	InstanceID         uuid.UUID
 	AuthServiceMock    bool `env:"AUTH_SERVICE_MOCK" env-default:"false"`
@@ -73,17 +77,66 @@ type HttpServerConfig struct {
 	ReadTimeout  time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"30s"`
 	WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"30s"`
 	IdleTimeout  time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"30s"`
+	// metrics listener, keep it unreachable from outside
+	InternalHost string `env:"HTTP_INTERNAL_HOST" env-default:"127.0.0.1"`
+	InternalPort string `env:"HTTP_INTERNAL_PORT" env-default:"8091"`
 }
 
 type StreamServerConfig struct {
 	Host     string `env:"STREAM_SERVER_HOST" env-default:"127.0.0.1"`
 	Port     string `env:"STREAM_SERVER_PORT" env-default:"1985"`
 	Endpoint string `env:"STREAM_SERVER_API_ENDPOINT" env-default:"/api/v1"`
+	// "{channel}" is replaced with channel name
+	SnapshotURL string `env:"STREAM_SERVER_SNAPSHOT_URL" env-default:"http://127.0.0.1:8080/live/{channel}.png"`
 }
 
 type UpdateConfig struct {
-	LivestreamsTimeout time.Duration `env:"UPDATE_LIVESTREAMS_TIMEOUT_SECONDS" env-default:"15s"`
-	CategoriesTimeout  time.Duration `env:"UPDATE_CATEGORIES_TIMEOUT_SECONDS" env-default:"10s"`
+	LivestreamsTimeout       time.Duration `env:"UPDATE_LIVESTREAMS_TIMEOUT_SECONDS" env-default:"15s"`
+	CategoriesTimeout        time.Duration `env:"UPDATE_CATEGORIES_TIMEOUT_SECONDS" env-default:"10s"`
+	ViewersFlushTimeout      time.Duration `env:"UPDATE_VIEWERS_FLUSH_TIMEOUT_SECONDS" env-default:"30s"`
+	ReconcileTimeout         time.Duration `env:"UPDATE_RECONCILE_TIMEOUT_SECONDS" env-default:"30s"`
+	BanCleanupTimeout        time.Duration `env:"UPDATE_BAN_CLEANUP_TIMEOUT_SECONDS" env-default:"1m"`
+	SubscriptionRenewTimeout time.Duration `env:"UPDATE_SUBSCRIPTION_RENEW_TIMEOUT_SECONDS" env-default:"1m"`
+}
+
+type JWTConfig struct {
+	// hmac secret for tokens without kid
+	Secret     string `env:"JWT_SECRET"`
+	SecretFile string `env:"JWT_SECRET_FILE"`
+	// file name without extension is kid, see auth.KeysConfig
+	KeysDir     string        `env:"JWT_KEYS_DIR"`
+	JWKSURL     string        `env:"JWT_JWKS_URL"`
+	JWKSRefresh time.Duration `env:"JWT_JWKS_REFRESH_SECONDS" env-default:"5m"`
+	Algorithms  []string      `env:"JWT_ALGORITHMS" env-default:"HS256"`
+	Issuer      string        `env:"JWT_ISSUER"`
+	Audience    string        `env:"JWT_AUDIENCE"`
+	// kid of the key the mock auth client signs with
+	SigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+}
+
+type MailConfig struct {
+	// smtp or log
+	Driver       string `env:"MAIL_DRIVER" env-default:"log"`
+	SMTPHost     string `env:"MAIL_SMTP_HOST" env-default:"127.0.0.1"`
+	SMTPPort     string `env:"MAIL_SMTP_PORT" env-default:"587"`
+	SMTPUsername string `env:"MAIL_SMTP_USERNAME"`
+	SMTPPassword string `env:"MAIL_SMTP_PASSWORD"`
+	From         string `env:"MAIL_FROM" env-default:"noreply@twitchy.local"`
+	// messages of the log driver are also appended to the file
+	SinkFile string `env:"MAIL_SINK_FILE"`
+	// "{token}" is replaced with verification token
+	VerifyURL string `env:"MAIL_VERIFY_URL" env-default:"http://localhost:3000/verify?token={token}"`
+	ResetURL  string `env:"MAIL_RESET_URL" env-default:"http://localhost:3000/password/reset?token={token}"`
+}
+
+type PaymentConfig struct {
+	// only fake is available, it accepts every charge without contacting anyone
+	Driver string `env:"PAYMENT_DRIVER" env-default:"fake"`
+}
+
+type WebhookConfig struct {
+	// has to match the "secret" query parameter of the srs hook urls, webhooks are rejected while empty
+	Secret string `env:"WEBHOOK_SECRET"`
 }
 
 type PostgresConfig struct {
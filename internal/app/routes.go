@@ -3,6 +3,8 @@ package app
 import (
 	"log/slog"
 	"net/http"
+	appAuth "twitchy-api/internal/app/auth"
+	"twitchy-api/internal/app/authz"
 	"twitchy-api/internal/auth"
 	authStorage "twitchy-api/internal/auth/storage"
 	"twitchy-api/internal/category"
@@ -13,7 +15,10 @@ import (
 	followStorage "twitchy-api/internal/follow/storage"
 	"twitchy-api/internal/health"
 	"twitchy-api/internal/livestream"
+	livestreamService "twitchy-api/internal/livestream/service"
 	livestreamStorage "twitchy-api/internal/livestream/storage"
+	"twitchy-api/internal/subscription"
+	subscriptionStorage "twitchy-api/internal/subscription/storage"
 	"twitchy-api/internal/user"
 	userStorage "twitchy-api/internal/user/storage"
 
@@ -25,32 +30,49 @@ import (
 func addRoutes(mux *http.ServeMux,
 	log *slog.Logger,
 	authMw mware,
+	optionalAuthMw mware,
+	az *authz.Authorizer,
+	keys *appAuth.Keys,
 	cr *categoryStorage.RepositoryImpl,
 	lsr *livestreamStorage.RepositoryImpl,
+	lsu *livestreamService.Updater,
 	chr *channelStorage.RepositoryImpl,
 	as *authStorage.ServiceImpl,
 	fr *followStorage.RepositoryImpl,
-	ur *userStorage.RepositoryImpl) {
+	ur *userStorage.RepositoryImpl,
+	ss *subscriptionStorage.ServiceImpl,
+	webhookSecret string) {
 	apiMux := http.NewServeMux()
 
-	livestreamsHandler := livestream.NewHandler(log, lsr)
-	apiMux.HandleFunc("GET /livestreams", livestreamsHandler.List)
-	apiMux.HandleFunc("GET /livestreams/{id}", livestreamsHandler.Get)
-	apiMux.HandleFunc("GET /users/{username}/livestream", livestreamsHandler.GetByUsername)
+	// authentication is optional here, it only fills is_following/is_subscriber
+	livestreamsHandler := livestream.NewHandler(log, lsr, fr, ss)
+	apiMux.HandleFunc("GET /livestreams", optionalAuthMw(livestreamsHandler.List))
+	apiMux.HandleFunc("GET /livestreams/{id}", optionalAuthMw(livestreamsHandler.Get))
+	apiMux.HandleFunc("GET /livestreams/{id}/viewers", livestreamsHandler.Viewers)
+	apiMux.HandleFunc("GET /users/{username}/livestream", optionalAuthMw(livestreamsHandler.GetByUsername))
+	apiMux.HandleFunc("GET /channels/{channel}/broadcasts", livestreamsHandler.Broadcasts)
 
 	// {identifier} is either int id or category link (e.g. "path-of-exile")
 	categoriesHandler := category.NewHandler(log, cr)
 	apiMux.HandleFunc("GET /categories", categoriesHandler.List)
 	apiMux.HandleFunc("GET /categories/{identifier}", categoriesHandler.Get)
-	apiMux.HandleFunc("POST /categories", authMw(categoriesHandler.Post))
-	apiMux.HandleFunc("PATCH /categories/{identifier}", authMw(categoriesHandler.Patch))
+	manageCategories := az.Require(authz.CategoriesManage, nil)
+	apiMux.HandleFunc("POST /categories", authMw(manageCategories(categoriesHandler.Post)))
+	apiMux.HandleFunc("PATCH /categories/{identifier}", authMw(manageCategories(categoriesHandler.Patch)))
 	// NOTE: currently deleting a category which id is in tc_livestream table is not possible
 	// due to non-nullable fk constraint (which is fine but might want to change later)
-	apiMux.HandleFunc("DELETE /categories/{identifier}", authMw(categoriesHandler.Delete))
+	apiMux.HandleFunc("DELETE /categories/{identifier}", authMw(manageCategories(categoriesHandler.Delete)))
 
 	authHandler := auth.NewHandler(log, as)
 	apiMux.HandleFunc("POST /auth/signin", authHandler.SignIn)
 	apiMux.HandleFunc("POST /auth/signup", authHandler.SignUp)
+	apiMux.HandleFunc("POST /auth/refresh", authHandler.Refresh)
+	apiMux.HandleFunc("POST /auth/signout", authMw(authHandler.SignOut))
+	apiMux.HandleFunc("POST /auth/signout-all", authMw(authHandler.SignOutAll))
+	apiMux.HandleFunc("POST /auth/verify", authHandler.Verify)
+	apiMux.HandleFunc("POST /auth/verify/resend", authMw(authHandler.ResendVerification))
+	apiMux.HandleFunc("POST /auth/password/forgot", authHandler.ForgotPassword)
+	apiMux.HandleFunc("POST /auth/password/reset", authHandler.ResetPassword)
 
 	followHandler := follow.NewHandler(log, fr)
 	apiMux.HandleFunc("GET /follow", followHandler.List)
@@ -58,16 +80,34 @@ func addRoutes(mux *http.ServeMux,
 	apiMux.HandleFunc("POST /follow/{username}", authMw(followHandler.Post))
 	apiMux.HandleFunc("DELETE /follow/{username}", authMw(followHandler.Delete))
 
-	userHandler := user.NewHandler(log, ur)
-	apiMux.HandleFunc("GET /users", authMw(userHandler.List))
+	userHandler := user.NewHandler(log, ur, az)
+	apiMux.HandleFunc("GET /users", authMw(az.Require(authz.UsersList, nil)(userHandler.List)))
 	apiMux.HandleFunc("GET /users/{id}", authMw(userHandler.Get))
-	apiMux.HandleFunc("POST /users", userHandler.Post)
-	apiMux.HandleFunc("PATCH /users/{id}", authMw(userHandler.Patch))
-	apiMux.HandleFunc("DELETE /users/{id}", authMw(userHandler.Delete))
+	apiMux.HandleFunc("POST /users", authHandler.CreateUser)
+	apiMux.HandleFunc("PATCH /users/{id}", authMw(az.Require(authz.UsersEdit, authz.PathUser("id"))(userHandler.Patch)))
+	apiMux.HandleFunc("DELETE /users/{id}", authMw(az.Require(authz.UsersDelete, authz.PathUser("id"))(userHandler.Delete)))
 
-	channelHandler := channel.NewHandler(log, chr)
+	channelHandler := channel.NewHandler(log, chr, fr)
 	apiMux.HandleFunc("GET /channels/{channel}", channelHandler.Get)
-	apiMux.HandleFunc("PATCH /channels/{channel}", authMw(channelHandler.Patch))
+	apiMux.HandleFunc("GET /channels/{channel}/followers", followHandler.Followers)
+	apiMux.HandleFunc("PATCH /channels/{channel}", authMw(az.Require(authz.ChannelsEdit, authz.PathChannel("channel"))(channelHandler.Patch)))
+	manageModerators := az.Require(authz.ModeratorsManage, authz.PathChannel("channel"))
+	apiMux.HandleFunc("GET /channels/{channel}/moderators", channelHandler.ListModerators)
+	apiMux.HandleFunc("POST /channels/{channel}/moderators", authMw(manageModerators(channelHandler.AddModerator)))
+	apiMux.HandleFunc("DELETE /channels/{channel}/moderators/{username}", authMw(manageModerators(channelHandler.RemoveModerator)))
+	moderate := az.Require(authz.ChannelsModerate, authz.PathChannel("channel"))
+	apiMux.HandleFunc("GET /channels/{channel}/bans", authMw(moderate(channelHandler.ListBans)))
+	apiMux.HandleFunc("POST /channels/{channel}/bans", authMw(moderate(channelHandler.Ban)))
+	apiMux.HandleFunc("DELETE /channels/{channel}/bans/{username}", authMw(moderate(channelHandler.Unban)))
+
+	subscriptionHandler := subscription.NewHandler(log, ss)
+	manageSubscriptions := az.Require(authz.SubscriptionsManage, authz.PathChannel("channel"))
+	apiMux.HandleFunc("GET /channels/{channel}/subscription/tiers", subscriptionHandler.ListTiers)
+	apiMux.HandleFunc("POST /channels/{channel}/subscription/tiers", authMw(manageSubscriptions(subscriptionHandler.PostTier)))
+	apiMux.HandleFunc("DELETE /channels/{channel}/subscription/tiers/{id}", authMw(manageSubscriptions(subscriptionHandler.DeleteTier)))
+	apiMux.HandleFunc("GET /channels/{channel}/subscription", authMw(subscriptionHandler.Get))
+	apiMux.HandleFunc("POST /channels/{channel}/subscription", authMw(subscriptionHandler.Post))
+	apiMux.HandleFunc("DELETE /channels/{channel}/subscription", authMw(subscriptionHandler.Delete))
 
 	apiMux.HandleFunc("GET /health", health.Get)
 
@@ -75,19 +115,17 @@ func addRoutes(mux *http.ServeMux,
 
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
-	// mux.HandleFunc("POST /webhooks/livestreams", livestreamsWebhooksHandler)
+	// srs http hooks, not a part of public api
+	webhookHandler := livestream.NewWebhookHandler(log, lsu, webhookSecret)
+	mux.HandleFunc("POST /webhooks/livestreams", webhookHandler.Handle)
+
+	// public keys of tokens signed by the mock auth client
+	mux.HandleFunc("GET /.well-known/jwks.json", keys.JWKSHandler)
+
+	// thumbnails are kept in redis, so any instance serves them
+	thumbnailHandler := livestream.NewThumbnailHandler(log, lsu)
+	mux.HandleFunc("GET /static/livestreamthumbs/{id}/{name}", thumbnailHandler.Get)
 
 	fileserver := http.FileServer(http.Dir("./static"))
 	mux.Handle("/static/", http.StripPrefix("/static/", fileserver))
 }
-
-// TODO: events
-// func livestreamsWebhooksHandler(w http.ResponseWriter, r *http.Request) {
-// 	var p streamserver.StreamStartedPayload
-// 	err := json.NewDecoder(r.Body).Decode(&p)
-// 	if err != nil {
-// 		fmt.Printf("bad: %+v\n", err)
-// 	}
-
-// 	fmt.Println("good:", p)
-// }
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"twitchy-api/internal/lib/sl"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	warmUpLockKey = "cache_warmup_lock"
+	// also the longest time other replicas wait for the warm up
+	warmUpLockTTL  = time.Minute
+	warmUpPollRate = 500 * time.Millisecond
+)
+
+// deletes the lock only if it is still held by the instance
+var releaseWarmUpLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// fills redis caches from pg so the api doesn't serve empty lists after redis was flushed.
+// only the replica that takes the lock performs it, others wait until it is done
+func (a *App) warmUpCaches(ctx context.Context) {
+	const op = "app.warmUpCaches"
+
+	ok, err := a.rdb.SetNX(ctx, warmUpLockKey, a.instanceID, warmUpLockTTL).Result()
+	if err != nil {
+		a.log.Error("acquire warm up lock", sl.Err(err), sl.Op(op))
+		return
+	}
+
+	if !ok {
+		a.log.Info("waiting for cache warm up by another instance")
+		a.waitWarmUp(ctx)
+		return
+	}
+
+	defer func() {
+		err := releaseWarmUpLock.Run(ctx, a.rdb, []string{warmUpLockKey}, a.instanceID).Err()
+		if err != nil {
+			a.log.Error("release warm up lock", sl.Err(err), sl.Op(op))
+		}
+	}()
+
+	start := time.Now()
+
+	categories, err := a.CategoryRepo.WarmUp(ctx)
+	if err != nil {
+		a.log.Error("warm up categories", sl.Err(err), sl.Op(op))
+	}
+
+	livestreams, err := a.LivestreamRepo.WarmUp(ctx)
+	if err != nil {
+		a.log.Error("warm up livestreams", sl.Err(err), sl.Op(op))
+	}
+
+	a.log.Info("caches warmed up",
+		slog.Int("categories", categories),
+		slog.Int("livestreams", livestreams),
+		slog.Duration("took", time.Since(start)))
+}
+
+// blocks until the lock is released or expires
+func (a *App) waitWarmUp(ctx context.Context) {
+	const op = "app.waitWarmUp"
+
+	ticker := time.NewTicker(warmUpPollRate)
+	defer ticker.Stop()
+
+	deadline := time.After(warmUpLockTTL)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			a.log.Warn("cache warm up by another instance is taking too long, not waiting anymore", sl.Op(op))
+			return
+		case <-ticker.C:
+			n, err := a.rdb.Exists(ctx, warmUpLockKey).Result()
+			if err != nil {
+				a.log.Error("check warm up lock", sl.Err(err), sl.Op(op))
+				return
+			}
+
+			if n == 0 {
+				return
+			}
+		}
+	}
+}
@@ -6,4 +6,17 @@ var (
 	ErrWrongCredentials = errors.New("wrong credentials")
 	ErrAlreadyExists    = errors.New("user already exists")
 	ErrNotFound         = errors.New("user not found")
+	ErrInvalidToken     = errors.New("invalid or expired refresh token")
+	ErrTokenReused      = errors.New("refresh token has already been used")
+	ErrInvalidEmail     = errors.New("invalid email")
+	ErrEmailTaken       = errors.New("email is already taken")
+	ErrNoEmail          = errors.New("user has no email")
+	ErrAlreadyVerified  = errors.New("email is already verified")
+	ErrVerifyToken      = errors.New("invalid or expired verification token")
+	ErrResendTooSoon    = errors.New("verification email was sent recently, try again later")
+	ErrResetToken       = errors.New("invalid or expired password reset token")
+	ErrTooManyAttempts  = errors.New("too many attempts, try again later")
+	ErrWeakPassword     = errors.New("password is too weak")
+	ErrUsernameRequired = errors.New("username required")
+	ErrPasswordRequired = errors.New("password required")
 )
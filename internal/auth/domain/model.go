@@ -8,6 +8,15 @@ func (m Token) MarshalBinary() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+type UserCreate struct {
+	// optional
+	Email    string
+	Username string
+	Password string
+	// optional
+	Pfp string
+}
+
 type UserInfo struct {
 	Id       int32
 	Username string
@@ -5,17 +5,30 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 	"time"
+	appAuth "twitchy-api/internal/app/auth"
 	d "twitchy-api/internal/auth/domain"
 	"twitchy-api/internal/lib/handler"
-	"twitchy-api/internal/lib/sl"
+	"twitchy-api/internal/lib/password"
 	api "twitchy-api/pkg/api/auth"
+	userApi "twitchy-api/pkg/api/user"
+	"unicode/utf8"
 )
 
 type Service interface {
 	SignIn(ctx context.Context, username, password string) (*d.TokenPair, error)
-	SignUp(ctx context.Context, email, username, password string) (*d.TokenPair, error)
+	SignUp(ctx context.Context, u d.UserCreate) (*d.TokenPair, error)
+	Create(ctx context.Context, u d.UserCreate) (int32, error)
+	Refresh(ctx context.Context, refresh string) (*d.TokenPair, error)
+	SignOut(ctx context.Context, access string, claims *appAuth.Claims, refresh string) error
+	SignOutAll(ctx context.Context, access string, claims *appAuth.Claims, refresh string) error
+	Verify(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, id int32, username string) error
+	ForgotPassword(ctx context.Context, username, ip string) error
+	ResetPassword(ctx context.Context, token, password string) error
 }
 
 type Handler struct {
@@ -78,10 +91,10 @@ func (h Handler) SignIn(w http.ResponseWriter, r *http.Request) {
 //	@Produce		json
 //	@Param			request	body		api.RegisterRequest		true	"Registration data"
 //	@Success		200		{object}	api.RegisterResponse	"Access token"
-//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request"
-//	@Failure		409		{object}	handler.ErrorResponse	"User already exists"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request, missing username/password, weak password, invalid email"
+//	@Failure		409		{object}	handler.ErrorResponse	"User or email already exists"
 //	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
-//	@Router			/signup [post]
+//	@Router			/auth/signup [post]
 func (h Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 	const op = "signing up"
 
@@ -92,12 +105,141 @@ func (h Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenPair, err := h.as.SignUp(r.Context(), request.Email, request.Username, request.Password)
+	if errs := validateUserCreate("username", request.Username, request.Password); len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	tokenPair, err := h.as.SignUp(r.Context(), d.UserCreate{
+		Email:    request.Email,
+		Username: request.Username,
+		Password: request.Password,
+		Pfp:      request.Pfp})
+	if err != nil {
+		h.userCreateError(w, op, err)
+		return
+	}
+
+	tokenCookie := createTokenCookie(string(tokenPair.Refresh), 720)
+	http.SetCookie(w, &tokenCookie)
+
+	json.NewEncoder(w).Encode(api.RegisterResponse{Access: string(tokenPair.Access)})
+}
+
+// CreateUser godoc
+//
+//	@Summary		Create user account
+//	@Description	Register new user account without signing in, kept for older clients. New clients use /auth/signup
+//	@Tags			Users
+//	@Accept			json
+//	@Param			request	body		userApi.PostRequest	true	"User creation data"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request, missing name/password, weak password"
+//	@Failure		409		{object}	handler.ErrorResponse	"User already exists"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/users [post]
+func (h Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	const op = "creating user"
+
+	var request userApi.PostRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	if errs := validateUserCreate("name", request.Name, request.Password); len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	_, err = h.as.Create(r.Context(), d.UserCreate{
+		Username: request.Name,
+		Password: request.Password,
+		Pfp:      request.Pfp.Value})
+	if err != nil {
+		h.userCreateError(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errors of the user creation request, the username is reported under nameField
+func validateUserCreate(nameField, username, pass string) map[string]error {
+	errs := make(map[string]error)
+
+	if username == "" {
+		errs[nameField] = d.ErrUsernameRequired
+	}
+
+	if pass == "" {
+		errs["password"] = d.ErrPasswordRequired
+	}
+
+	if pass != "" && utf8.RuneCountInString(pass) < password.MinLength {
+		errs["password"] = d.ErrWeakPassword
+	}
+
+	return errs
+}
+
+func (h Handler) userCreateError(w http.ResponseWriter, op string, err error) {
+	if errors.Is(err, d.ErrAlreadyExists) {
+		handler.Error(h.log, w, op, err, http.StatusConflict, d.ErrAlreadyExists.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrEmailTaken) {
+		handler.Error(h.log, w, op, err, http.StatusConflict, d.ErrEmailTaken.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrInvalidEmail) {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrInvalidEmail.Error())
+		return
+	}
+
+	if errors.Is(err, password.ErrTooLong) {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, password.ErrTooLong.Error())
+		return
+	}
+
+	handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+}
+
+// Refresh godoc
+//
+//	@Summary		Refresh access token
+//	@Description	Exchange refresh token from the cookie for a new access token and rotate the refresh token
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	api.RefreshResponse		"Access token"
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing, invalid or reused refresh token"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/refresh [post]
+func (h Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "refreshing token"
+
+	cookie, err := r.Cookie(refreshCookie)
 	if err != nil {
-		h.log.Error(op, sl.Err(err))
+		handler.Error(h.log, w, op, err, http.StatusUnauthorized, d.ErrInvalidToken.Error())
+		return
+	}
+
+	tokenPair, err := h.as.Refresh(r.Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, d.ErrInvalidToken) || errors.Is(err, d.ErrTokenReused) {
+			expired := createTokenCookie("", -1)
+			http.SetCookie(w, &expired)
 
-		if errors.Is(err, d.ErrAlreadyExists) {
-			handler.Error(h.log, w, op, err, http.StatusConflict, d.ErrAlreadyExists.Error())
+			msg := d.ErrInvalidToken.Error()
+			if errors.Is(err, d.ErrTokenReused) {
+				msg = d.ErrTokenReused.Error()
+			}
+
+			handler.Error(h.log, w, op, err, http.StatusUnauthorized, msg)
 			return
 		}
 
@@ -108,12 +250,238 @@ func (h Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 	tokenCookie := createTokenCookie(string(tokenPair.Refresh), 720)
 	http.SetCookie(w, &tokenCookie)
 
-	json.NewEncoder(w).Encode(api.RegisterResponse{Access: string(tokenPair.Access)})
+	json.NewEncoder(w).Encode(api.RefreshResponse{Access: string(tokenPair.Access)})
+}
+
+// Verify godoc
+//
+//	@Summary		Verify email
+//	@Description	Confirm email with the token from the verification link
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			request	body	api.VerifyRequest	true	"Verification token"
+//	@Success		204
+//	@Failure		400	{object}	handler.ErrorResponse	"Invalid request or token"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/verify [post]
+func (h Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	const op = "verifying email"
+
+	var request api.VerifyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Token == "" {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	err = h.as.Verify(r.Context(), request.Token)
+	if err != nil {
+		if errors.Is(err, d.ErrVerifyToken) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrVerifyToken.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification godoc
+//
+//	@Summary		Resend verification email
+//	@Description	Send a new verification link, the previous one stops working
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		204
+//	@Failure		400	{object}	handler.ErrorResponse	"User has no email"
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing, invalid or revoked access token"
+//	@Failure		409	{object}	handler.ErrorResponse	"Email is already verified"
+//	@Failure		429	{object}	handler.ErrorResponse	"Verification email was sent recently"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/verify/resend [post]
+func (h Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	const op = "resending verification email"
+
+	claims, ok := appAuth.FromContext(r.Context())
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	err := h.as.ResendVerification(r.Context(), claims.Id, claims.Username)
+	if err != nil {
+		if errors.Is(err, d.ErrNoEmail) || errors.Is(err, d.ErrNotFound) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrNoEmail.Error())
+			return
+		}
+
+		if errors.Is(err, d.ErrAlreadyVerified) {
+			handler.Error(h.log, w, op, err, http.StatusConflict, d.ErrAlreadyVerified.Error())
+			return
+		}
+
+		if errors.Is(err, d.ErrResendTooSoon) {
+			handler.Error(h.log, w, op, err, http.StatusTooManyRequests, d.ErrResendTooSoon.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword godoc
+//
+//	@Summary		Request password reset
+//	@Description	Send password reset link to the verified email of the user. Responds the same way whether the user exists or not
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			request	body	api.ForgotPasswordRequest	true	"Username"
+//	@Success		204
+//	@Failure		400	{object}	handler.ErrorResponse	"Invalid request"
+//	@Failure		429	{object}	handler.ErrorResponse	"Too many attempts for the username or ip"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/password/forgot [post]
+func (h Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "requesting password reset"
+
+	var request api.ForgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Username == "" {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	err = h.as.ForgotPassword(r.Context(), request.Username, ip)
+	if err != nil {
+		if errors.Is(err, d.ErrTooManyAttempts) {
+			handler.Error(h.log, w, op, err, http.StatusTooManyRequests, d.ErrTooManyAttempts.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Reset password
+//	@Description	Set new password with the token from the reset link. Every session of the user is signed out
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			request	body	api.ResetPasswordRequest	true	"Reset token and new password"
+//	@Success		204
+//	@Failure		400	{object}	handler.ErrorResponse	"Invalid request, token or weak password"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/password/reset [post]
+func (h Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "resetting password"
+
+	var request api.ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Token == "" {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	err = h.as.ResetPassword(r.Context(), request.Token, request.Password)
+	if err != nil {
+		if errors.Is(err, d.ErrResetToken) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrResetToken.Error())
+			return
+		}
+
+		if errors.Is(err, d.ErrWeakPassword) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrWeakPassword.Error())
+			return
+		}
+
+		if errors.Is(err, password.ErrTooLong) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, password.ErrTooLong.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// SignOut godoc
+//
+//	@Summary		Sign out
+//	@Description	Revoke access token and refresh token of the current session
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		204
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing, invalid or revoked access token"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/signout [post]
+func (h Handler) SignOut(w http.ResponseWriter, r *http.Request) {
+	h.signOut(w, r, "signing out", h.as.SignOut)
+}
+
+// SignOutAll godoc
+//
+//	@Summary		Sign out everywhere
+//	@Description	Revoke every access and refresh token of the user
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		204
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing, invalid or revoked access token"
+//	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/auth/signout-all [post]
+func (h Handler) SignOutAll(w http.ResponseWriter, r *http.Request) {
+	h.signOut(w, r, "signing out everywhere", h.as.SignOutAll)
+}
+
+type signOutFunc = func(ctx context.Context, access string, claims *appAuth.Claims, refresh string) error
+
+func (h Handler) signOut(w http.ResponseWriter, r *http.Request, op string, signOut signOutFunc) {
+	claims, ok := appAuth.FromContext(r.Context())
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	access := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	var refresh string
+	cookie, err := r.Cookie(refreshCookie)
+	if err == nil {
+		refresh = cookie.Value
+	}
+
+	err = signOut(r.Context(), access, claims, refresh)
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	expired := createTokenCookie("", -1)
+	http.SetCookie(w, &expired)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const refreshCookie = "refreshToken"
+
 func createTokenCookie(token string, hours int) http.Cookie {
 	return http.Cookie{
-		Name:     "refreshToken",
+		Name:     refreshCookie,
 		Value:    token,
 		Expires:  time.Now().Add(time.Duration(hours) * time.Hour),
 		HttpOnly: true,
@@ -1,16 +1,52 @@
 -- name: AuthSelectUser :one
+SELECT
+    id,
+    app_role,
+    password,
+    password_algo
+FROM
+    tc_user
+WHERE
+    name = $1;
+
+
+-- name: AuthSelectUserByName :one
 SELECT
     id,
     app_role
 FROM
     tc_user
 WHERE
-    name = $1 AND password = $2;
+    name = $1;
+
+
 
+-- name: AuthUpdatePassword :exec
+UPDATE
+    tc_user
+SET
+    password = $2,
+    password_algo = $3
+WHERE
+    id = $1;
 
--- name: AuthInsertUser :exec
-INSERT INTO
-    tc_user(name, password)
-VALUES
-    ($1, $2);
 
+
+-- name: AuthSelectEmail :one
+SELECT
+    email,
+    email_verified
+FROM
+    tc_user
+WHERE
+    id = $1;
+
+
+
+-- name: AuthVerifyEmail :exec
+UPDATE
+    tc_user
+SET
+    email_verified = TRUE
+WHERE
+    id = $1;
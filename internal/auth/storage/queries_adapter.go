@@ -5,6 +5,7 @@ import (
 	"errors"
 	d "twitchy-api/internal/auth/domain"
 	"twitchy-api/internal/external/db"
+	"twitchy-api/internal/lib/password"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -14,25 +15,93 @@ type queriesAdapter struct {
 	queries *db.Queries
 }
 
-func (q *queriesAdapter) Select(ctx context.Context, arg db.AuthSelectUserParams) (*db.AuthSelectUserRow, error) {
-	res, err := q.queries.AuthSelectUser(ctx, arg)
+func (q *queriesAdapter) Select(ctx context.Context, name string) (*db.AuthSelectUserRow, error) {
+	res, err := q.queries.AuthSelectUser(ctx, name)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, d.ErrNotFound
 	}
 
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (q *queriesAdapter) SelectByName(ctx context.Context, name string) (*db.AuthSelectUserByNameRow, error) {
+	res, err := q.queries.AuthSelectUserByName(ctx, name)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, d.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
 	return &res, nil
 }
 
-func (q *queriesAdapter) Insert(ctx context.Context, arg db.AuthInsertUserParams) error {
-	err := q.queries.AuthInsertUser(ctx, arg)
+// the only way users are created, shared by sign up and user creation
+func (q *queriesAdapter) Insert(ctx context.Context, arg db.UserInsertParams) (int32, error) {
+	id, err := q.queries.UserInsert(ctx, arg)
 
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
 		if pgErr.Code == db.CodeUniqueConstraint {
-			return d.ErrAlreadyExists
+			if pgErr.ConstraintName == db.ConstraintUserEmail {
+				return 0, d.ErrEmailTaken
+			}
+
+			return 0, d.ErrAlreadyExists
 		}
 	}
 
-	return err
+	return id, err
+}
+
+func (q *queriesAdapter) SelectEmail(ctx context.Context, id int32) (*db.AuthSelectEmailRow, error) {
+	res, err := q.queries.AuthSelectEmail(ctx, id)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, d.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// sets password through the user update query, returns name of the user
+func (q *queriesAdapter) UpdatePasswordByID(ctx context.Context, id int32, hash string) (string, error) {
+	u, err := q.queries.UserSelect(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", d.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = q.queries.UserUpdate(ctx, db.UserUpdateParams{
+		ID:               id,
+		PasswordDoUpdate: true,
+		Password:         hash,
+		PasswordAlgo:     password.AlgoCurrent,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return u.Name, nil
+}
+
+func (q *queriesAdapter) VerifyEmail(ctx context.Context, id int32) error {
+	return q.queries.AuthVerifyEmail(ctx, id)
+}
+
+func (q *queriesAdapter) UpdatePassword(ctx context.Context, arg db.AuthUpdatePasswordParams) error {
+	return q.queries.AuthUpdatePassword(ctx, arg)
 }
@@ -3,56 +3,466 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+	appAuth "twitchy-api/internal/app/auth"
 	d "twitchy-api/internal/auth/domain"
 	ext "twitchy-api/internal/external/auth"
 	"twitchy-api/internal/external/db"
+	"twitchy-api/internal/external/mailer"
+	"twitchy-api/internal/lib/password"
+	"twitchy-api/internal/lib/sl"
+	"unicode/utf8"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
 type Client interface {
 	GetPair(ctx context.Context, ui ext.UserInfo) (*ext.TokenPair, error)
+	Blacklist(ctx context.Context, access, refresh string) error
+}
+
+// used when refresh token has no expiration and as ttl of revoked families
+const refreshMaxTTL = time.Duration(14400) * time.Minute
+
+const (
+	verifyTTL    = 24 * time.Hour
+	verifyResend = time.Minute
+
+	resetTTL = time.Hour
+	// reset requests per hour
+	resetPerUser = 3
+	resetPerIP   = 10
+)
+
+// links sent in emails, "{token}" is replaced with the token
+type MailLinks struct {
+	Verify string
+	Reset  string
 }
 
 type ServiceImpl struct {
-	pool *pgxpool.Pool
-	ac   Client
+	log      *slog.Logger
+	pool     *pgxpool.Pool
+	ac       Client
+	refresh  *refreshStore
+	verify   *tokenStore
+	reset    *tokenStore
+	limits   *limitStore
+	denylist *appAuth.Denylist
+	keys     *appAuth.Keys
+	ml       mailer.Mailer
+	links    MailLinks
 }
 
-func NewService(ac Client, pool *pgxpool.Pool) *ServiceImpl {
-	return &ServiceImpl{ac: ac, pool: pool}
+func NewService(log *slog.Logger,
+	ac Client,
+	pool *pgxpool.Pool,
+	rdb *redis.Client,
+	keys *appAuth.Keys,
+	ml mailer.Mailer,
+	links MailLinks) *ServiceImpl {
+	return &ServiceImpl{log: log,
+		ac:       ac,
+		pool:     pool,
+		refresh:  &refreshStore{rdb: rdb},
+		verify:   &tokenStore{rdb: rdb, kind: "email_verify", ttl: verifyTTL},
+		reset:    &tokenStore{rdb: rdb, kind: "password_reset", ttl: resetTTL},
+		limits:   &limitStore{rdb: rdb},
+		denylist: appAuth.NewDenylist(rdb),
+		keys:     keys,
+		ml:       ml,
+		links:    links}
 }
 
-func (s *ServiceImpl) SignIn(ctx context.Context, username, password string) (*d.TokenPair, error) {
+func (s *ServiceImpl) SignIn(ctx context.Context, username, pass string) (*d.TokenPair, error) {
+	const op = "auth.ServiceImpl.SignIn"
+
 	q := queriesAdapter{queries: db.New(s.pool)}
-	ui, err := q.Select(ctx, db.AuthSelectUserParams{Name: username, Password: password})
+	ui, err := q.Select(ctx, username)
 	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
+		if errors.Is(err, d.ErrNotFound) {
+			password.VerifyDummy(pass)
 			return nil, d.ErrWrongCredentials
 		}
 
 		return nil, err
 	}
 
-	pair, err := s.ac.GetPair(ctx, ext.UserInfo{Id: ui.ID, Username: username, Role: string(ui.AppRole)})
+	ok, err := password.Verify(ui.Password, string(ui.PasswordAlgo), pass)
 	if err != nil {
 		return nil, err
 	}
 
-	return &d.TokenPair{Access: d.Token(pair.Access), Refresh: d.Token(pair.Refresh)}, nil
+	if !ok {
+		return nil, d.ErrWrongCredentials
+	}
+
+	if password.NeedsRehash(ui.Password, string(ui.PasswordAlgo)) {
+		hash, err := password.Hash(pass)
+		if err == nil {
+			err = q.UpdatePassword(ctx, db.AuthUpdatePasswordParams{
+				ID:           ui.ID,
+				Password:     hash,
+				PasswordAlgo: password.AlgoCurrent})
+		}
+
+		// the old hash still works, so failed rehash is retried on the next sign in
+		if err != nil {
+			s.log.Warn("rehashing password", sl.Err(err), sl.Op(op), slog.Int("user_id", int(ui.ID)))
+		}
+	}
+
+	return s.issue(ctx, ext.UserInfo{Id: ui.ID, Username: username, Role: string(ui.AppRole)}, "")
+}
+
+// creates the user and signs them in. tokens are issued once the user is stored,
+// if that fails the user is left created and can sign in
+func (s *ServiceImpl) SignUp(ctx context.Context, u d.UserCreate) (*d.TokenPair, error) {
+	id, err := s.Create(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, ext.UserInfo{Id: id, Username: u.Username, Role: appAuth.RoleUser}, "")
+}
+
+// creates the user and returns its id. if email is present the user is sent a verification link
+func (s *ServiceImpl) Create(ctx context.Context, u d.UserCreate) (int32, error) {
+	const op = "auth.ServiceImpl.Create"
+
+	var address string
+	if u.Email != "" {
+		addr, err := mail.ParseAddress(u.Email)
+		if err != nil || addr.Name != "" {
+			return 0, d.ErrInvalidEmail
+		}
+
+		address = addr.Address
+	}
+
+	hash, err := password.Hash(u.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	q := queriesAdapter{queries: db.New(s.pool)}
+	id, err := q.Insert(ctx, db.UserInsertParams{
+		Name:         u.Username,
+		Password:     hash,
+		PasswordAlgo: password.AlgoCurrent,
+		Email:        pgtype.Text{String: address, Valid: address != ""},
+		Pfp:          pgtype.Text{String: u.Pfp, Valid: u.Pfp != ""}})
+	if err != nil {
+		return 0, err
+	}
+
+	if address != "" {
+		// the user is already created, the link can be requested again
+		err := s.sendVerification(ctx, id, u.Username, address)
+		if err != nil {
+			s.log.Error("sending verification email", sl.Err(err), sl.Op(op), slog.Int("user_id", int(id)))
+		}
+	}
+
+	return id, nil
+}
+
+// marks email of the token owner as verified
+func (s *ServiceImpl) Verify(ctx context.Context, token string) error {
+	id, ok, err := s.verify.consume(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return d.ErrVerifyToken
+	}
+
+	q := queriesAdapter{queries: db.New(s.pool)}
+	return q.VerifyEmail(ctx, id)
 }
 
-func (s *ServiceImpl) SignUp(ctx context.Context, email, username, password string) (*d.TokenPair, error) {
+// sends a new verification link, the previous one stops working
+func (s *ServiceImpl) ResendVerification(ctx context.Context, id int32, username string) error {
 	q := queriesAdapter{queries: db.New(s.pool)}
-	err := q.Insert(ctx, db.AuthInsertUserParams{Name: username, Password: password})
+	res, err := q.SelectEmail(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !res.Email.Valid {
+		return d.ErrNoEmail
+	}
+
+	if res.EmailVerified {
+		return d.ErrAlreadyVerified
+	}
+
+	ok, err := s.limits.allow(ctx, fmt.Sprintf("email_verify_resend:%d", id), 1, verifyResend)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return d.ErrResendTooSoon
+	}
+
+	return s.sendVerification(ctx, id, username, res.Email.String)
+}
+
+func (s *ServiceImpl) sendVerification(ctx context.Context, id int32, username, address string) error {
+	token, err := s.verify.create(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	link := strings.ReplaceAll(s.links.Verify, "{token}", token)
+
+	return s.ml.Send(ctx, mailer.Message{
+		To:      address,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nconfirm your email by following the link: %s\n\nThe link expires in %d hours.",
+			username, link, int(verifyTTL.Hours()))})
+}
+
+// sends password reset link to the verified email of the user. unknown users and users
+// without verified email are silently skipped, so the response doesn't reveal them
+func (s *ServiceImpl) ForgotPassword(ctx context.Context, username, ip string) error {
+	ok, err := s.limits.allow(ctx, "password_forgot_ip:"+ip, resetPerIP, time.Hour)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		ok, err = s.limits.allow(ctx, "password_forgot_user:"+username, resetPerUser, time.Hour)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !ok {
+		return d.ErrTooManyAttempts
+	}
+
+	q := queriesAdapter{queries: db.New(s.pool)}
+	ui, err := q.SelectByName(ctx, username)
+	if errors.Is(err, d.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := q.SelectEmail(ctx, ui.ID)
+	if err != nil {
+		return err
+	}
+
+	if !res.Email.Valid || !res.EmailVerified {
+		return nil
+	}
+
+	token, err := s.reset.create(ctx, ui.ID)
+	if err != nil {
+		return err
+	}
+
+	link := strings.ReplaceAll(s.links.Reset, "{token}", token)
+
+	return s.ml.Send(ctx, mailer.Message{
+		To:      res.Email.String,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nset a new password by following the link: %s\n\n"+
+			"The link expires in %d minutes. If you didn't request it, ignore this email.",
+			username, link, int(resetTTL.Minutes()))})
+}
+
+// sets new password of the token owner and signs the user out everywhere
+func (s *ServiceImpl) ResetPassword(ctx context.Context, token, pass string) error {
+	if utf8.RuneCountInString(pass) < password.MinLength {
+		return d.ErrWeakPassword
+	}
+
+	// hashing first, so a too long password doesn't burn the token
+	hash, err := password.Hash(pass)
+	if err != nil {
+		return err
+	}
+
+	id, ok, err := s.reset.consume(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return d.ErrResetToken
+	}
+
+	q := queriesAdapter{queries: db.New(s.pool)}
+	username, err := q.UpdatePasswordByID(ctx, id, hash)
+	if err != nil {
+		return err
+	}
+
+	return s.denylist.RevokeAll(ctx, username, refreshMaxTTL)
+}
+
+// exchanges refresh token for a new pair. the presented token can't be used again,
+// presenting it again revokes every token obtained from it
+func (s *ServiceImpl) Refresh(ctx context.Context, refresh string) (*d.TokenPair, error) {
+	claims, err := s.parseRefresh(ctx, refresh)
 	if err != nil {
 		return nil, err
 	}
 
-	pair, err := s.ac.GetPair(ctx, ext.UserInfo{Username: username, Role: "user"})
+	revoked, err := s.denylist.IsRevoked(ctx, claims, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, fmt.Errorf("%w: token of %s is revoked", d.ErrInvalidToken, claims.Username)
+	}
+
+	family, err := s.refresh.family(ctx, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if family == "" {
+		return nil, d.ErrInvalidToken
+	}
+
+	res, err := s.refresh.rotate(ctx, refresh, family)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res {
+	case rotateOK:
+	case rotateReused:
+		err := s.refresh.revokeFamily(ctx, family, refreshMaxTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: family %s of %s is revoked", d.ErrTokenReused, family, claims.Username)
+	default:
+		return nil, d.ErrInvalidToken
+	}
+
+	// role might have changed since the token was issued
+	q := queriesAdapter{queries: db.New(s.pool)}
+	ui, err := q.SelectByName(ctx, claims.Username)
+	if err != nil {
+		if errors.Is(err, d.ErrNotFound) {
+			return nil, d.ErrInvalidToken
+		}
+
+		return nil, err
+	}
+
+	return s.issue(ctx, ext.UserInfo{Id: ui.ID, Username: claims.Username, Role: string(ui.AppRole)}, family)
+}
+
+// revokes access token and refresh token of the current session.
+// refresh token is optional and ignored if it is invalid or belongs to someone else
+func (s *ServiceImpl) SignOut(ctx context.Context, access string, claims *appAuth.Claims, refresh string) error {
+	err := s.denylist.Revoke(ctx, claims, access, refreshMaxTTL)
+	if err != nil {
+		return err
+	}
+
+	refreshClaims, err := s.parseRefresh(ctx, refresh)
+	if err != nil || refreshClaims.Username != claims.Username {
+		refresh = ""
+	} else {
+		family, err := s.refresh.family(ctx, refresh)
+		if err != nil {
+			return err
+		}
+
+		if family != "" {
+			err = s.refresh.revokeFamily(ctx, family, refreshMaxTTL)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.denylist.Revoke(ctx, refreshClaims, refresh, refreshMaxTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.ac.Blacklist(ctx, access, refresh)
+}
+
+// revokes every token of the user issued so far, including the ones of the current session
+func (s *ServiceImpl) SignOutAll(ctx context.Context, access string, claims *appAuth.Claims, refresh string) error {
+	err := s.denylist.RevokeAll(ctx, claims.Username, refreshMaxTTL)
+	if err != nil {
+		return err
+	}
+
+	refreshClaims, err := s.parseRefresh(ctx, refresh)
+	if err != nil || refreshClaims.Username != claims.Username {
+		refresh = ""
+	}
+
+	return s.ac.Blacklist(ctx, access, refresh)
+}
+
+// gets new pair from auth service and registers its refresh token in the family.
+// empty family starts a new one
+func (s *ServiceImpl) issue(ctx context.Context, ui ext.UserInfo, family string) (*d.TokenPair, error) {
+	pair, err := s.ac.GetPair(ctx, ui)
+	if err != nil {
+		return nil, err
+	}
+
+	if family == "" {
+		family = uuid.NewString()
+	}
+
+	err = s.refresh.add(ctx, string(pair.Refresh), family, expiresAt(string(pair.Refresh)))
 	if err != nil {
 		return nil, err
 	}
 
 	return &d.TokenPair{Access: d.Token(pair.Access), Refresh: d.Token(pair.Refresh)}, nil
 }
+
+func (s *ServiceImpl) parseRefresh(ctx context.Context, refresh string) (*appAuth.Claims, error) {
+	claims := &appAuth.Claims{}
+	err := s.keys.Parse(ctx, refresh, claims, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", d.ErrInvalidToken, err)
+	}
+
+	// tokens without typ pass here, access tokens among them are not in any refresh family
+	if claims.Type != appAuth.TypeRefresh && claims.Type != appAuth.TypeLegacy {
+		return nil, fmt.Errorf("%w: not a refresh token", d.ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// expiration of a token issued by the auth service, signature is not checked
+func expiresAt(token string) time.Time {
+	claims := &jwt.RegisteredClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil || claims.ExpiresAt == nil {
+		return time.Now().Add(refreshMaxTTL)
+	}
+
+	return claims.ExpiresAt.Time
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fixed window counters, keys are "rate_limits:<key>"
+type limitStore struct {
+	rdb *redis.Client
+}
+
+// counts the attempt and reports whether it is within limit attempts per window
+func (l *limitStore) allow(ctx context.Context, key string, limit int64, window time.Duration) (bool, error) {
+	key = "rate_limits:" + key
+
+	var count *redis.IntCmd
+	_, err := l.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		count = p.Incr(ctx, key)
+		p.ExpireNX(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count.Val() <= limit, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// issued refresh tokens. tokens are stored by their hash and grouped into families:
+// every token obtained by refreshing belongs to the family of the token it replaced.
+// presenting already rotated token means it was stolen, so the whole family is revoked
+//
+// keys are "refresh_tokens:<hash>" (hash with family and status fields)
+// and "refresh_families_revoked:<family>"
+type refreshStore struct {
+	rdb *redis.Client
+}
+
+const (
+	refreshActive  = "active"
+	refreshRotated = "rotated"
+)
+
+type rotateResult int
+
+const (
+	rotateUnknown rotateResult = iota
+	rotateOK
+	rotateReused
+	rotateRevoked
+)
+
+// marks active token as rotated. fails if the token is unknown, rotated or its family is revoked
+var rotateScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+	return 0
+end
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 3
+end
+if status == 'rotated' then
+	return 2
+end
+redis.call('HSET', KEYS[1], 'status', 'rotated')
+return 1
+`)
+
+func (r *refreshStore) add(ctx context.Context, token string, family string, expiresAt time.Time) error {
+	key := r.key(token)
+
+	_, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, "family", family, "status", refreshActive)
+		p.ExpireAt(ctx, key, expiresAt)
+		return nil
+	})
+
+	return err
+}
+
+// returns empty string if the token is unknown
+func (r *refreshStore) family(ctx context.Context, token string) (string, error) {
+	family, err := r.rdb.HGet(ctx, r.key(token), "family").Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+
+	return family, err
+}
+
+func (r *refreshStore) rotate(ctx context.Context, token string, family string) (rotateResult, error) {
+	res, err := rotateScript.Run(ctx, r.rdb, []string{r.key(token), r.familyKey(family)}).Int()
+	if err != nil {
+		return rotateUnknown, err
+	}
+
+	return rotateResult(res), nil
+}
+
+// ttl should be not less than lifetime of the longest living token of the family
+func (r *refreshStore) revokeFamily(ctx context.Context, family string, ttl time.Duration) error {
+	return r.rdb.Set(ctx, r.familyKey(family), 1, ttl).Err()
+}
+
+func (r *refreshStore) key(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("refresh_tokens:%s", hex.EncodeToString(sum[:]))
+}
+
+func (r *refreshStore) familyKey(family string) string {
+	return fmt.Sprintf("refresh_families_revoked:%s", family)
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// single-use tokens bound to a user (email verification, password reset).
+// tokens are stored by their hash, every user has at most one valid token of a kind,
+// so creating a new one invalidates the previous
+//
+// keys are "<kind>_tokens:<hash>" (user id) and "<kind>_users:<id>" (hash of the current token)
+type tokenStore struct {
+	rdb  *redis.Client
+	kind string
+	ttl  time.Duration
+}
+
+func (t *tokenStore) create(ctx context.Context, id int32) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	hash := t.hash(token)
+
+	prev, err := t.rdb.SetArgs(ctx, t.userKey(id), hash, redis.SetArgs{TTL: t.ttl, Get: true}).Result()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	_, err = t.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		if prev != "" {
+			p.Del(ctx, t.tokenKey(prev))
+		}
+		p.Set(ctx, t.tokenKey(hash), id, t.ttl)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// returns false if the token is unknown or expired. token can be consumed only once
+func (t *tokenStore) consume(ctx context.Context, token string) (int32, bool, error) {
+	res, err := t.rdb.GetDel(ctx, t.tokenKey(t.hash(token))).Result()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	id, err := strconv.ParseInt(res, 10, 32)
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = t.rdb.Del(ctx, t.userKey(int32(id))).Err()
+	if err != nil {
+		return 0, false, err
+	}
+
+	return int32(id), true, nil
+}
+
+func (t *tokenStore) hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *tokenStore) tokenKey(hash string) string {
+	return fmt.Sprintf("%s_tokens:%s", t.kind, hash)
+}
+
+func (t *tokenStore) userKey(id int32) string {
+	return fmt.Sprintf("%s_users:%d", t.kind, id)
+}
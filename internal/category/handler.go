@@ -7,7 +7,6 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"twitchy-api/internal/app/auth"
 	d "twitchy-api/internal/category/domain"
 	"twitchy-api/internal/lib/handler"
 	api "twitchy-api/pkg/api/category"
@@ -178,25 +177,15 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 //	@Param			request	body	c.PostRequest	true	"Category data"
 //	@Security		BearerAuth
 //	@Success		204	"Category created successfully"
-//	@Failure		400	{object}	handler.ErrorResponse	"Invalid auth, insufficient permissions, or malformed request"
+//	@Failure		400	{object}	handler.ErrorResponse	"Malformed request"
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing or invalid auth"
+//	@Failure		403	{object}	handler.ErrorResponse	"Insufficient permissions"
 //	@Failure		500	{object}	handler.ErrorResponse	"Internal server error or category already exists"
 //	@Router			/categories [post]
 func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 	const op = "creating category"
 
 	ctx := r.Context()
-	user, ok := auth.FromContext(ctx)
-
-	if !ok {
-		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
-	if user.Role != auth.RoleStaff {
-		handler.Error(h.log, w, op, handler.ErrNotAllowed, http.StatusBadRequest, handler.ErrNotAllowed.Error())
-		return
-	}
-
 	var req api.PostRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
@@ -240,25 +229,15 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 //	@Param			request		body	c.PatchRequest	true	"Fields to update"
 //	@Security		BearerAuth
 //	@Success		204	"Category updated successfully"
-//	@Failure		400	{object}	handler.ErrorResponse	"Invalid auth, insufficient permissions, or malformed request"
+//	@Failure		400	{object}	handler.ErrorResponse	"Malformed request"
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing or invalid auth"
+//	@Failure		403	{object}	handler.ErrorResponse	"Insufficient permissions"
 //	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
 //	@Router			/categories/{identifier} [patch]
 func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 	const op = "updating category"
 
 	ctx := r.Context()
-	user, ok := auth.FromContext(ctx)
-
-	if !ok {
-		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
-	if user.Role != auth.RoleStaff {
-		handler.Error(h.log, w, op, handler.ErrNotAllowed, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
 	var req api.PatchRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
@@ -324,25 +303,14 @@ func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 //	@Param			auth		header	string	true	"Bearer token"			format(jwt)
 //	@Security		BearerAuth
 //	@Success		204	"Category deleted successfully"
-//	@Failure		400	{object}	handler.ErrorResponse	"Invalid or insufficient auth permissions"
+//	@Failure		401	{object}	handler.ErrorResponse	"Missing or invalid auth"
+//	@Failure		403	{object}	handler.ErrorResponse	"Insufficient permissions"
 //	@Failure		500	{object}	handler.ErrorResponse	"Internal server error"
 //	@Router			/categories/{identifier} [delete]
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	const op = "deleting category"
 
 	ctx := r.Context()
-	user, ok := auth.FromContext(ctx)
-
-	if !ok {
-		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
-	if user.Role != auth.RoleStaff {
-		handler.Error(h.log, w, op, handler.ErrNotAllowed, http.StatusBadRequest, handler.ErrNotAllowed.Error())
-		return
-	}
-
 	identifier := r.PathValue("identifier")
 	categoryId, err := strconv.Atoi(identifier)
 	if err != nil {
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -87,6 +88,43 @@ func (r *cache) add(ctx context.Context, cat d.Category) error {
 
 }
 
+// same as add, but leaves the category as is if it is already cached. false if it was
+func (r *cache) addMissing(ctx context.Context, cat d.Category) (bool, error) {
+	key := r.categories.key(strconv.Itoa(int(cat.Id)))
+
+	added := false
+	err := r.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil || exists > 0 {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			r.linkMap.addTx(ctx, p, cat.Link, int(cat.Id))
+			r.sorted.addTx(ctx, p, int(cat.Viewers), strconv.Itoa(int(cat.Id)))
+			r.categories.addTx(ctx, p, cat)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		added = true
+		return nil
+	}, key)
+
+	// the category was written while it was being checked, that one is fresher
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("pipeline failed: %w", err)
+	}
+
+	return added, nil
+}
+
 func (r *cache) list(ctx context.Context, f d.CategoryFilter) ([]d.Category, error) {
 	start := (int64(f.Page) - 1) * int64(f.Count)
 	count := int64(f.Count)
@@ -17,6 +17,26 @@ LIMIT $1
 OFFSET $2;
 
 
+-- name: CategorySelectAll :many
+SELECT
+    c.id AS category_id,
+    c.name AS category_name,
+    c.link AS category_link,
+    is_safe,
+    viewers,
+    image,
+    t.id AS tag_id,
+    t.name AS tag_name
+FROM
+    tc_category c
+LEFT OUTER JOIN
+    tc_category_tag ct ON ct.id_category = c.id
+LEFT OUTER JOIN
+    tc_tag t ON t.id = ct.id_tag
+ORDER BY
+    c.id;
+
+
 -- name: CategorySelect :one
 SELECT
     *
@@ -59,6 +79,16 @@ WHERE
 RETURNING *;
 
 
+-- name: CategoryUpdateViewersMany :exec
+UPDATE tc_category AS c
+SET
+    viewers = v.viewers
+FROM
+    (SELECT unnest(@ids::int[]) AS id, unnest(@viewers::int[]) AS viewers) AS v
+WHERE
+    c.id = v.id AND c.viewers <> v.viewers;
+
+
 -- name: CategoryDeleteTags :exec
 DELETE FROM
     tc_category_tag
@@ -109,3 +109,11 @@ func (q *queriesAdapter) DeleteByLink(ctx context.Context, link string) (db.TcCa
 func (q *queriesAdapter) DeleteTags(ctx context.Context, id int32) error {
 	return q.queries.CategoryDeleteTags(ctx, id)
 }
+
+func (q *queriesAdapter) UpdateViewersMany(ctx context.Context, arg db.CategoryUpdateViewersManyParams) error {
+	return q.queries.CategoryUpdateViewersMany(ctx, arg)
+}
+
+func (q *queriesAdapter) SelectAll(ctx context.Context) ([]db.CategorySelectAllRow, error) {
+	return q.queries.CategorySelectAll(ctx)
+}
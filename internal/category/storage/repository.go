@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	d "twitchy-api/internal/category/domain"
 	"twitchy-api/internal/external/db"
 
@@ -191,6 +192,86 @@ func (r *RepositoryImpl) UpdateViewers(ctx context.Context, id int, viewers int)
 	return r.cache.updateViewers(ctx, id, viewers)
 }
 
+// loads categories with their tags from pg into the cache. categories that are already cached
+// are left as is since the cache is fresher. returns count of added categories
+func (r *RepositoryImpl) WarmUp(ctx context.Context) (int, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+	rows, err := q.SelectAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// rows are ordered by category id, one row per tag
+	var categories []d.Category
+	for _, row := range rows {
+		if len(categories) == 0 || categories[len(categories)-1].Id != row.CategoryID {
+			categories = append(categories, d.Category{
+				Id:        row.CategoryID,
+				IsSafe:    row.IsSafe,
+				Thumbnail: row.Image,
+				Name:      row.CategoryName,
+				Link:      row.CategoryLink,
+				Viewers:   row.Viewers,
+				Tags:      d.CategoryTags{},
+			})
+		}
+
+		if row.TagID.Valid {
+			last := &categories[len(categories)-1]
+			last.Tags = append(last.Tags, d.CategoryTag{Id: row.TagID.Int32, Name: row.TagName.String})
+		}
+	}
+
+	added := 0
+	for _, cat := range categories {
+		ok, err := r.cache.addMissing(ctx, cat)
+		if err != nil {
+			return added, err
+		}
+
+		if ok {
+			added += 1
+		}
+	}
+
+	return added, nil
+}
+
+// copies viewers of all categories from the cache to tc_category in one query
+func (r *RepositoryImpl) SyncViewers(ctx context.Context) (int, error) {
+	all, err := r.cache.sorted.getAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(all) == 0 {
+		return 0, nil
+	}
+
+	arg := db.CategoryUpdateViewersManyParams{
+		Ids:     make([]int32, 0, len(all)),
+		Viewers: make([]int32, 0, len(all)),
+	}
+
+	for _, z := range all {
+		id, err := strconv.Atoi(fmt.Sprint(z.Member))
+		if err != nil {
+			continue
+		}
+
+		arg.Ids = append(arg.Ids, int32(id))
+		arg.Viewers = append(arg.Viewers, int32(z.Score))
+	}
+
+	q := queriesAdapter{queries: db.New(r.pool)}
+	err = q.UpdateViewersMany(ctx, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(arg.Ids), nil
+}
+
 func (r *RepositoryImpl) updateTags(ctx context.Context, q *queriesAdapter, id int32, tags []int32) error {
 	int32Ids := make([]int32, len(tags))
 
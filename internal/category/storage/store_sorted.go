@@ -27,6 +27,11 @@ func (s *sortedStore) get(ctx context.Context, start int64, count int64) ([]stri
 	}).Result()
 }
 
+// all category ids with their viewers
+func (s *sortedStore) getAll(ctx context.Context) ([]redis.Z, error) {
+	return s.rdb.ZRangeWithScores(ctx, s.key(), 0, -1).Result()
+}
+
 func (s *sortedStore) updateViewers(ctx context.Context, id string, viewers int32) error {
 	return s.rdb.ZAdd(ctx, s.key(), redis.Z{
 		Score:  float64(viewers),
@@ -3,5 +3,12 @@ package domain
 import "errors"
 
 var (
-	ErrNotPresent = errors.New("channel is not present in the request")
+	ErrNotPresent    = errors.New("channel is not present in the request")
+	ErrNotFound      = errors.New("channel not found")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrNoUsername    = errors.New("username is not present in the request")
+	ErrSelfModerator = errors.New("channel owner can't be a moderator of own channel")
+	ErrSelfBan       = errors.New("channel owner can't be banned in own channel")
+	ErrBanModerator  = errors.New("moderators can't ban other moderators of the channel")
+	ErrBanDuration   = errors.New("timeout duration must be positive and not longer than 14 days")
 )
@@ -36,3 +36,42 @@ type ChannelUpdate struct {
 	Links       []ChannelLink
 	Tags        []ChannelTag
 }
+
+type Moderator struct {
+	Id      int32
+	Name    string
+	Pfp     string
+	AddedAt time.Time
+}
+
+// longest timeout, longer ones should be permanent bans
+const MaxTimeout = 14 * 24 * time.Hour
+
+type Ban struct {
+	UserId     int32
+	Name       string
+	Pfp        string
+	BannedFrom time.Time
+	// zero for permanent bans
+	ExpiresAt time.Time
+	Reason    string
+	// name of the owner or moderator who issued the ban, empty if the account is deleted
+	BannedBy string
+}
+
+type BanCreate struct {
+	Channel  string
+	Username string
+	// zero for permanent ban, timeout otherwise
+	Duration time.Duration
+	Reason   string
+	BannedBy int32
+	// whether moderators of the channel can be banned, only users who can manage moderators may do so
+	BanModerators bool
+}
+
+type BanSearch struct {
+	Channel string
+	Page    int
+	Count   int
+}
@@ -3,8 +3,13 @@ package channel
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
+	"twitchy-api/internal/app/auth"
+	"twitchy-api/internal/app/authz"
 	d "twitchy-api/internal/channel/domain"
 	"twitchy-api/internal/lib/handler"
 	api "twitchy-api/pkg/api/channel"
@@ -13,15 +18,26 @@ import (
 type Repository interface {
 	Get(ctx context.Context, username string) (*d.Channel, error)
 	Update(ctx context.Context, upd d.ChannelUpdate) error
+	Moderators(ctx context.Context, channel string) ([]d.Moderator, error)
+	AddModerator(ctx context.Context, channel, username string, addedBy int32) error
+	RemoveModerator(ctx context.Context, channel, username string) error
+	Bans(ctx context.Context, search d.BanSearch) ([]d.Ban, error)
+	Ban(ctx context.Context, ban d.BanCreate) error
+	Unban(ctx context.Context, channel, username string) error
+}
+
+type FollowerCounter interface {
+	FollowerCount(ctx context.Context, channel string) (int64, error)
 }
 
 type Handler struct {
 	cr  Repository
+	fc  FollowerCounter
 	log *slog.Logger
 }
 
-func NewHandler(log *slog.Logger, s Repository) *Handler {
-	return &Handler{cr: s, log: log}
+func NewHandler(log *slog.Logger, s Repository, fc FollowerCounter) *Handler {
+	return &Handler{cr: s, fc: fc, log: log}
 }
 
 // Get retrieves a channel by its ID (username of owner)
@@ -52,6 +68,12 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	followers, err := h.fc.FollowerCount(r.Context(), channel)
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
 	json.NewEncoder(w).Encode(api.GetResponse{
 		IsBanned:        res.IsBanned,
 		IsPartner:       res.IsPartner,
@@ -59,6 +81,7 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		FirstLivestream: res.FirstLivestream,
 		LastLivestream:  res.LastLivestream,
 		Description:     res.Description,
+		Followers:       followers,
 		// Links:           res.Links,
 		// Tags:            res.Tags,
 	})
@@ -80,3 +103,312 @@ func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListModerators godoc
+//
+//	@Summary		List channel moderators
+//	@Description	Users appointed by the channel owner to moderate the channel
+//	@Tags			Channels
+//	@Produce		json
+//	@Param			channel	path		string						true	"Channel identifier"
+//	@Success		200		{object}	api.ListModeratorsResponse	"Moderators"
+//	@Failure		404		{object}	handler.ErrorResponse		"Channel not found"
+//	@Failure		500		{object}	handler.ErrorResponse		"Internal server error"
+//	@Router			/channels/{channel}/moderators [get]
+func (h *Handler) ListModerators(w http.ResponseWriter, r *http.Request) {
+	const op = "listing moderators"
+
+	mods, err := h.cr.Moderators(r.Context(), r.PathValue("channel"))
+	if err != nil {
+		if errors.Is(err, d.ErrNotFound) {
+			handler.Error(h.log, w, op, err, http.StatusNotFound, d.ErrNotFound.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	res := make([]api.Moderator, len(mods))
+	for i, m := range mods {
+		res[i] = api.Moderator(m)
+	}
+
+	json.NewEncoder(w).Encode(api.ListModeratorsResponse{Moderators: res})
+}
+
+// AddModerator godoc
+//
+//	@Summary		Add channel moderator
+//	@Description	Appoint a user as a moderator of the channel (owner or admin only), appointing twice is a no-op
+//	@Tags			Channels
+//	@Accept			json
+//	@Security		BearerAuth
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Param			request	body		api.AddModeratorRequest	true	"User to appoint"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request or appointing the owner"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to manage moderators"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel or user not found"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/moderators [post]
+func (h *Handler) AddModerator(w http.ResponseWriter, r *http.Request) {
+	const op = "adding moderator"
+
+	ctx := r.Context()
+	user, ok := auth.FromContext(ctx)
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	var req api.AddModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	if req.Username == "" {
+		handler.Error(h.log, w, op, d.ErrNoUsername, http.StatusBadRequest, d.ErrNoUsername.Error())
+		return
+	}
+
+	err := h.cr.AddModerator(ctx, r.PathValue("channel"), req.Username, user.Id)
+	if err != nil {
+		h.moderatorError(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveModerator godoc
+//
+//	@Summary		Remove channel moderator
+//	@Description	Remove a moderator of the channel (owner or admin only), removing a non-moderator is a no-op
+//	@Tags			Channels
+//	@Security		BearerAuth
+//	@Param			channel		path		string	true	"Channel identifier"
+//	@Param			username	path		string	true	"Moderator username"
+//	@Success		204			{object}	nil
+//	@Failure		401			{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403			{object}	handler.ErrorResponse	"Not allowed to manage moderators"
+//	@Failure		404			{object}	handler.ErrorResponse	"Channel or user not found"
+//	@Failure		500			{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/moderators/{username} [delete]
+func (h *Handler) RemoveModerator(w http.ResponseWriter, r *http.Request) {
+	const op = "removing moderator"
+
+	err := h.cr.RemoveModerator(r.Context(), r.PathValue("channel"), r.PathValue("username"))
+	if err != nil {
+		h.moderatorError(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBans godoc
+//
+//	@Summary		List channel bans
+//	@Description	Get paginated list of active bans and timeouts of the channel, most recent first (owner, moderators or staff only)
+//	@Tags			Channels
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Param			page	query		string					false	"Page number (default: 1)"
+//	@Param			count	query		string					false	"Items per page (default: 10, max: 100)"
+//	@Success		200		{object}	api.ListBansResponse	"Bans"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid page or count parameters"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to moderate the channel"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel not found"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/bans [get]
+func (h *Handler) ListBans(w http.ResponseWriter, r *http.Request) {
+	const op = "listing bans"
+
+	page := r.URL.Query().Get("page")
+	if page == "" {
+		page = "1"
+	}
+
+	errs := make(map[string]error)
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		errs["page"] = handler.ErrBadPage
+	}
+
+	if pageInt < 1 {
+		pageInt = 1
+	}
+
+	count := r.URL.Query().Get("count")
+	if count == "" {
+		count = "10"
+	}
+
+	countInt, err := strconv.Atoi(count)
+	if err != nil {
+		errs["count"] = handler.ErrBadCount
+	}
+
+	if len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	if countInt < 1 {
+		countInt = 10
+	}
+
+	if countInt > 100 {
+		countInt = 100
+	}
+
+	bans, err := h.cr.Bans(r.Context(), d.BanSearch{
+		Channel: r.PathValue("channel"),
+		Page:    pageInt,
+		Count:   countInt,
+	})
+	if err != nil {
+		h.moderatorError(w, op, err)
+		return
+	}
+
+	res := make([]api.Ban, len(bans))
+	for i, b := range bans {
+		res[i] = api.Ban{
+			UserId:     b.UserId,
+			Name:       b.Name,
+			Pfp:        b.Pfp,
+			BannedFrom: b.BannedFrom,
+			Reason:     b.Reason,
+			BannedBy:   b.BannedBy,
+		}
+
+		if !b.ExpiresAt.IsZero() {
+			res[i].ExpiresAt = &b.ExpiresAt
+		}
+	}
+
+	json.NewEncoder(w).Encode(api.ListBansResponse{Bans: res})
+}
+
+// Ban godoc
+//
+//	@Summary		Ban or time out user
+//	@Description	Ban a user in the channel permanently or for duration_seconds (owner, moderators or staff only). Moderators can be banned by the owner or admins only. Banned users can't follow the channel. Banning again replaces the previous ban
+//	@Tags			Channels
+//	@Accept			json
+//	@Security		BearerAuth
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Param			request	body		api.BanRequest			true	"User to ban"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request, duration or banning the owner"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to moderate the channel or to ban its moderators"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel or user not found"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/bans [post]
+func (h *Handler) Ban(w http.ResponseWriter, r *http.Request) {
+	const op = "banning user"
+
+	ctx := r.Context()
+	user, ok := auth.FromContext(ctx)
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	access, ok := authz.FromContext(ctx)
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	var req api.BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	errs := make(map[string]error)
+
+	if req.Username == "" {
+		errs["username"] = d.ErrNoUsername
+	}
+
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration < 0 || duration > d.MaxTimeout {
+		errs["duration_seconds"] = d.ErrBanDuration
+	}
+
+	if len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	err := h.cr.Ban(ctx, d.BanCreate{
+		Channel:       access.Channel,
+		Username:      req.Username,
+		Duration:      duration,
+		Reason:        req.Reason,
+		BannedBy:      user.Id,
+		BanModerators: access.Can(user, authz.ModeratorsManage),
+	})
+	if err != nil {
+		h.moderatorError(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unban godoc
+//
+//	@Summary		Unban user
+//	@Description	Lift a ban or timeout (owner, moderators or staff only), unbanning a user who is not banned is a no-op
+//	@Tags			Channels
+//	@Security		BearerAuth
+//	@Param			channel		path		string	true	"Channel identifier"
+//	@Param			username	path		string	true	"Banned username"
+//	@Success		204			{object}	nil
+//	@Failure		401			{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403			{object}	handler.ErrorResponse	"Not allowed to moderate the channel"
+//	@Failure		404			{object}	handler.ErrorResponse	"Channel or user not found"
+//	@Failure		500			{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/bans/{username} [delete]
+func (h *Handler) Unban(w http.ResponseWriter, r *http.Request) {
+	const op = "unbanning user"
+
+	err := h.cr.Unban(r.Context(), r.PathValue("channel"), r.PathValue("username"))
+	if err != nil {
+		h.moderatorError(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errors shared by moderator and ban endpoints
+func (h *Handler) moderatorError(w http.ResponseWriter, op string, err error) {
+	if errors.Is(err, d.ErrNotFound) || errors.Is(err, d.ErrUserNotFound) {
+		handler.Error(h.log, w, op, err, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrSelfModerator) || errors.Is(err, d.ErrSelfBan) {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrBanModerator) {
+		handler.Error(h.log, w, op, err, http.StatusForbidden, err.Error())
+		return
+	}
+
+	handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+}
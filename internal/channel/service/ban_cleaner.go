@@ -0,0 +1,52 @@
+package channel
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	TaskBanCleanup = "channel:ban_cleanup"
+	// every instance registers the task, the id keeps only one of them in the queue at a time
+	banCleanupTaskID = "channel_ban_cleanup"
+)
+
+type expiredBansDeleter interface {
+	DeleteExpiredBans(ctx context.Context) (int64, error)
+}
+
+type scheduler interface {
+	Schedule(every time.Duration, taskType string, payload []byte, taskId string) (string, error)
+}
+
+// removes timeouts that are over. expired rows are already ignored by reads,
+// so the cleanup only keeps tc_user_banned from growing
+type BanCleaner struct {
+	log   *slog.Logger
+	bans  expiredBansDeleter
+	sched scheduler
+}
+
+func NewBanCleaner(log *slog.Logger, bans expiredBansDeleter, sched scheduler) *BanCleaner {
+	return &BanCleaner{log: log, bans: bans, sched: sched}
+}
+
+// registers the periodic cleanup task
+func (c *BanCleaner) Schedule(every time.Duration) error {
+	_, err := c.sched.Schedule(every, TaskBanCleanup, nil, banCleanupTaskID)
+	return err
+}
+
+func (c *BanCleaner) HandleCleanupTask(ctx context.Context, _ []byte) error {
+	n, err := c.bans.DeleteExpiredBans(ctx)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		c.log.Debug("expired bans removed", slog.Int64("count", n))
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+-- name: BanUpsert :exec
+INSERT INTO tc_user_banned(id_user, id_channel, banned_from, expires_at, banned_by, reason)
+VALUES ($1, $2, now(), $3, $4, $5)
+ON CONFLICT (id_user, id_channel) DO UPDATE SET
+    banned_from = EXCLUDED.banned_from,
+    expires_at = EXCLUDED.expires_at,
+    banned_by = EXCLUDED.banned_by,
+    reason = EXCLUDED.reason;
+
+
+-- name: BanDelete :exec
+DELETE FROM
+    tc_user_banned
+WHERE
+    id_user = $1 AND id_channel = $2;
+
+
+-- name: BanDeleteFollow :execrows
+-- banned users stop following the channel
+DELETE FROM
+    tc_user_follow
+WHERE
+    id_user = $1 AND id_follow = $2;
+
+
+-- name: BanSelectMany :many
+SELECT
+    u.id,
+    u.name,
+    u.pfp,
+    b.banned_from,
+    b.expires_at,
+    b.reason,
+    m.name AS banned_by
+FROM
+    tc_user_banned b
+INNER JOIN
+    tc_user u ON u.id = b.id_user
+LEFT OUTER JOIN
+    tc_user m ON m.id = b.banned_by
+WHERE
+    b.id_channel = $1
+AND (b.expires_at IS NULL OR b.expires_at > now())
+ORDER BY
+    b.banned_from DESC, u.id
+LIMIT $2
+OFFSET $3;
+
+
+-- name: BanDeleteExpired :execrows
+DELETE FROM
+    tc_user_banned
+WHERE
+    expires_at IS NOT NULL AND expires_at <= now();
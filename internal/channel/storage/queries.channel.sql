@@ -15,3 +15,12 @@ FROM
     tc_user
 WHERE
     name = $1;
+
+
+-- name: ChannelSelectId :one
+SELECT
+    id
+FROM
+    tc_user
+WHERE
+    name = $1;
@@ -0,0 +1,49 @@
+-- name: ModeratorSelectUserId :one
+SELECT
+    id
+FROM
+    tc_user
+WHERE
+    name = $1;
+
+
+-- name: ModeratorSelectMany :many
+SELECT
+    u.id,
+    u.name,
+    u.pfp,
+    m.added_at
+FROM
+    tc_channel_moderator m
+INNER JOIN
+    tc_user u ON u.id = m.id_user
+WHERE
+    m.id_channel = $1
+ORDER BY
+    m.added_at, u.id;
+
+
+-- name: ModeratorExists :one
+SELECT EXISTS (
+    SELECT
+        1
+    FROM
+        tc_channel_moderator m
+    INNER JOIN
+        tc_user c ON c.id = m.id_channel
+    WHERE
+        c.name = $1 AND m.id_user = $2
+);
+
+
+-- name: ModeratorInsert :exec
+INSERT INTO tc_channel_moderator(id_channel, id_user, added_by)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+
+-- name: ModeratorDelete :exec
+DELETE FROM
+    tc_channel_moderator
+WHERE
+    id_channel = $1 AND id_user = $2;
@@ -2,7 +2,11 @@ package storage
 
 import (
 	"context"
+	"errors"
+	d "twitchy-api/internal/channel/domain"
 	"twitchy-api/internal/external/db"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type queriesAdapter struct {
@@ -12,3 +16,57 @@ type queriesAdapter struct {
 func (q *queriesAdapter) Select(ctx context.Context, name string) (db.ChannelSelectRow, error) {
 	return q.queries.ChannelSelect(ctx, name)
 }
+
+func (q *queriesAdapter) SelectChannelId(ctx context.Context, name string) (int32, error) {
+	id, err := q.queries.ChannelSelectId(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, d.ErrNotFound
+	}
+
+	return id, err
+}
+
+func (q *queriesAdapter) SelectUserId(ctx context.Context, name string) (int32, error) {
+	id, err := q.queries.ModeratorSelectUserId(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, d.ErrUserNotFound
+	}
+
+	return id, err
+}
+
+func (q *queriesAdapter) SelectModerators(ctx context.Context, channelId int32) ([]db.ModeratorSelectManyRow, error) {
+	return q.queries.ModeratorSelectMany(ctx, channelId)
+}
+
+func (q *queriesAdapter) IsModerator(ctx context.Context, arg db.ModeratorExistsParams) (bool, error) {
+	return q.queries.ModeratorExists(ctx, arg)
+}
+
+func (q *queriesAdapter) InsertModerator(ctx context.Context, arg db.ModeratorInsertParams) error {
+	return q.queries.ModeratorInsert(ctx, arg)
+}
+
+func (q *queriesAdapter) DeleteModerator(ctx context.Context, arg db.ModeratorDeleteParams) error {
+	return q.queries.ModeratorDelete(ctx, arg)
+}
+
+func (q *queriesAdapter) SelectBans(ctx context.Context, arg db.BanSelectManyParams) ([]db.BanSelectManyRow, error) {
+	return q.queries.BanSelectMany(ctx, arg)
+}
+
+func (q *queriesAdapter) UpsertBan(ctx context.Context, arg db.BanUpsertParams) error {
+	return q.queries.BanUpsert(ctx, arg)
+}
+
+func (q *queriesAdapter) DeleteFollow(ctx context.Context, arg db.BanDeleteFollowParams) (int64, error) {
+	return q.queries.BanDeleteFollow(ctx, arg)
+}
+
+func (q *queriesAdapter) DeleteBan(ctx context.Context, arg db.BanDeleteParams) error {
+	return q.queries.BanDelete(ctx, arg)
+}
+
+func (q *queriesAdapter) DeleteExpiredBans(ctx context.Context) (int64, error) {
+	return q.queries.BanDeleteExpired(ctx)
+}
@@ -3,19 +3,28 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	d "twitchy-api/internal/channel/domain"
 	"twitchy-api/internal/external/db"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// cached follower counts, kept in sync when a ban removes a follower
+type FollowerCounts interface {
+	AdjustFollowerCount(ctx context.Context, channelId int32, delta int64) error
+}
+
 type RepositoryImpl struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	counts FollowerCounts
 }
 
-func NewRepository(pool *pgxpool.Pool) *RepositoryImpl {
-	return &RepositoryImpl{pool: pool}
+func NewRepository(pool *pgxpool.Pool, counts FollowerCounts) *RepositoryImpl {
+	return &RepositoryImpl{pool: pool, counts: counts}
 }
 
 func (s *RepositoryImpl) Get(ctx context.Context, chann string) (*d.Channel, error) {
@@ -42,3 +51,245 @@ func (s *RepositoryImpl) Get(ctx context.Context, chann string) (*d.Channel, err
 func (s *RepositoryImpl) Update(ctx context.Context, upd d.ChannelUpdate) error {
 	return errors.New("not implemented")
 }
+
+func (s *RepositoryImpl) Moderators(ctx context.Context, channel string) ([]d.Moderator, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.SelectModerators(ctx, channelId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderators: %w", err)
+	}
+
+	mods := make([]d.Moderator, len(rows))
+	for i, row := range rows {
+		mods[i] = d.Moderator{
+			Id:      row.ID,
+			Name:    row.Name,
+			Pfp:     row.Pfp.String,
+			AddedAt: row.AddedAt.Time,
+		}
+	}
+
+	return mods, nil
+}
+
+// appoints the user as a moderator of the channel, appointing twice is a no-op.
+// addedBy is id of the user who appointed the moderator
+func (s *RepositoryImpl) AddModerator(ctx context.Context, channel, username string, addedBy int32) error {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, userId, err := s.moderatorIds(ctx, q, channel, username)
+	if err != nil {
+		return err
+	}
+
+	if channelId == userId {
+		return d.ErrSelfModerator
+	}
+
+	err = q.InsertModerator(ctx, db.ModeratorInsertParams{
+		IDChannel: channelId,
+		IDUser:    userId,
+		AddedBy:   pgtype.Int4{Int32: addedBy, Valid: addedBy != 0},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add moderator: %w", err)
+	}
+
+	return nil
+}
+
+// removing a user who is not a moderator is a no-op
+func (s *RepositoryImpl) RemoveModerator(ctx context.Context, channel, username string) error {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, userId, err := s.moderatorIds(ctx, q, channel, username)
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteModerator(ctx, db.ModeratorDeleteParams{
+		IDChannel: channelId,
+		IDUser:    userId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove moderator: %w", err)
+	}
+
+	return nil
+}
+
+// implements authz.ModeratorChecker
+func (s *RepositoryImpl) IsModerator(ctx context.Context, channel string, userId int32) (bool, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	isMod, err := q.IsModerator(ctx, db.ModeratorExistsParams{
+		Name:   channel,
+		IDUser: userId,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to determine whether user is moderator: %w", err)
+	}
+
+	return isMod, nil
+}
+
+// active bans and timeouts of the channel, most recent first
+func (s *RepositoryImpl) Bans(ctx context.Context, search d.BanSearch) ([]d.Ban, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, search.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.SelectBans(ctx, db.BanSelectManyParams{
+		IDChannel: channelId,
+		Limit:     int64(search.Count),
+		Offset:    int64((search.Page - 1) * search.Count),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bans: %w", err)
+	}
+
+	bans := make([]d.Ban, len(rows))
+	for i, row := range rows {
+		bans[i] = d.Ban{
+			UserId:     row.ID,
+			Name:       row.Name,
+			Pfp:        row.Pfp.String,
+			BannedFrom: row.BannedFrom.Time,
+			ExpiresAt:  row.ExpiresAt.Time,
+			Reason:     row.Reason.String,
+			BannedBy:   row.BannedBy.String,
+		}
+	}
+
+	return bans, nil
+}
+
+// bans the user in the channel and removes them from its followers,
+// banning again replaces the previous ban or timeout
+func (s *RepositoryImpl) Ban(ctx context.Context, ban d.BanCreate) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // nolint:errcheck
+
+	q := queriesAdapter{queries: db.New(s.pool).WithTx(tx)}
+
+	channelId, userId, err := s.moderatorIds(ctx, q, ban.Channel, ban.Username)
+	if err != nil {
+		return err
+	}
+
+	if channelId == userId {
+		return d.ErrSelfBan
+	}
+
+	if !ban.BanModerators {
+		isMod, err := q.IsModerator(ctx, db.ModeratorExistsParams{
+			Name:   ban.Channel,
+			IDUser: userId,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to determine whether user is moderator: %w", err)
+		}
+
+		if isMod {
+			return d.ErrBanModerator
+		}
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if ban.Duration > 0 {
+		expiresAt = pgtype.Timestamptz{Time: time.Now().Add(ban.Duration), Valid: true}
+	}
+
+	err = q.UpsertBan(ctx, db.BanUpsertParams{
+		IDUser:    userId,
+		IDChannel: channelId,
+		ExpiresAt: expiresAt,
+		BannedBy:  pgtype.Int4{Int32: ban.BannedBy, Valid: ban.BannedBy != 0},
+		Reason:    pgtype.Text{String: ban.Reason, Valid: ban.Reason != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ban: %w", err)
+	}
+
+	unfollowed, err := q.DeleteFollow(ctx, db.BanDeleteFollowParams{
+		IDUser:   userId,
+		IDFollow: channelId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove follow of banned user: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	// redis can't take part in the transaction, so the count follows right after the commit
+	if unfollowed > 0 {
+		err = s.counts.AdjustFollowerCount(ctx, channelId, -unfollowed)
+		if err != nil {
+			return fmt.Errorf("failed to update follower count: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// unbanning a user who is not banned is a no-op
+func (s *RepositoryImpl) Unban(ctx context.Context, channel, username string) error {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, userId, err := s.moderatorIds(ctx, q, channel, username)
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteBan(ctx, db.BanDeleteParams{
+		IDUser:    userId,
+		IDChannel: channelId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unban: %w", err)
+	}
+
+	return nil
+}
+
+// removes timeouts that are over, returns how many were removed
+func (s *RepositoryImpl) DeleteExpiredBans(ctx context.Context) (int64, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	n, err := q.DeleteExpiredBans(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired bans: %w", err)
+	}
+
+	return n, nil
+}
+
+// ids of the channel owner and the user
+func (s *RepositoryImpl) moderatorIds(ctx context.Context, q queriesAdapter, channel, username string) (int32, int32, error) {
+	channelId, err := q.SelectChannelId(ctx, channel)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	userId, err := q.SelectUserId(ctx, username)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return channelId, userId, nil
+}
@@ -74,7 +74,7 @@ func NewClient(
 }
 
 func (c *ClientImpl) GetRefresh(ctx context.Context, ui UserInfo) (*Token, error) {
-	res, err := c.api.NewRefresh(ctx, &auth_v1.NewRefreshRequest{Username: ui.Username})
+	res, err := c.api.NewRefresh(ctx, &auth_v1.NewRefreshRequest{Username: ui.Username, Role: ui.Role})
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +83,7 @@ func (c *ClientImpl) GetRefresh(ctx context.Context, ui UserInfo) (*Token, error
 }
 
 func (c *ClientImpl) GetAccess(ctx context.Context, ui UserInfo) (*Token, error) {
-	res, err := c.api.NewAccess(ctx, &auth_v1.NewAccessRequest{Username: ui.Username})
+	res, err := c.api.NewAccess(ctx, &auth_v1.NewAccessRequest{Username: ui.Username, Role: ui.Role})
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +105,12 @@ func (c *ClientImpl) GetPair(ctx context.Context, ui UserInfo) (*TokenPair, erro
 	return &TokenPair{Access: Token(*access), Refresh: Token(*refresh)}, nil
 }
 
+// makes auth service reject the tokens. either of them can be empty
+func (c *ClientImpl) Blacklist(ctx context.Context, access, refresh string) error {
+	_, err := c.api.Blacklist(ctx, &auth_v1.BlacklistRequest{Access: access, Refresh: refresh})
+	return err
+}
+
 func InterceptorLogger(l *slog.Logger) grpclog.Logger {
 	return grpclog.LoggerFunc(func(ctx context.Context, lvl grpclog.Level, msg string, fields ...any) {
 		l.Log(ctx, slog.Level(lvl), msg, fields...)
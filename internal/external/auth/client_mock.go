@@ -6,15 +6,23 @@ import (
 	"twitchy-api/internal/app/auth"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-type ClientMock struct{}
+// issues tokens signed with the signing key of auth.Keys
+type ClientMock struct {
+	keys *auth.Keys
+}
+
+func NewClientMock(keys *auth.Keys) *ClientMock {
+	return &ClientMock{keys: keys}
+}
 
 func (s *ClientMock) GetRefresh(ctx context.Context, ui UserInfo) (*Token, error) {
 	now := time.Now()
 	refreshExp := now.Add(refreshExpirationTime)
 
-	refresh, err := generateJWT(ui, refreshExp)
+	refresh, err := s.generateJWT(ui, auth.TypeRefresh, refreshExp)
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +36,7 @@ func (s *ClientMock) GetAccess(ctx context.Context, ui UserInfo) (*Token, error)
 	now := time.Now()
 	accessExp := now.Add(accessExpirationTime)
 
-	access, err := generateJWT(ui, accessExp)
+	access, err := s.generateJWT(ui, auth.TypeAccess, accessExp)
 	if err != nil {
 		return nil, err
 	}
@@ -39,34 +47,42 @@ func (s *ClientMock) GetAccess(ctx context.Context, ui UserInfo) (*Token, error)
 }
 
 func (s *ClientMock) GetPair(ctx context.Context, ui UserInfo) (*TokenPair, error) {
-	return createPair(ui)
+	return s.createPair(ui)
+}
+
+// revoked tokens are denied by auth.Denylist, the mock has nothing to keep
+func (s *ClientMock) Blacklist(ctx context.Context, access, refresh string) error {
+	return nil
 }
 
-func generateJWT(ui UserInfo, expirationTime time.Time) (string, error) {
+func (s *ClientMock) generateJWT(ui UserInfo, typ string, expirationTime time.Time) (string, error) {
 	claims := &auth.Claims{
 		Id:       ui.Id,
 		Username: ui.Username,
 		Role:     ui.Role,
+		Type:     typ,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// makes every token unique, otherwise tokens issued within the same second are identical
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(auth.JWTKey)
+	return s.keys.Sign(claims)
 }
 
-func createPair(ui UserInfo) (*TokenPair, error) {
+func (s *ClientMock) createPair(ui UserInfo) (*TokenPair, error) {
 	now := time.Now()
 	refreshExp := now.Add(refreshExpirationTime)
 	accessExp := now.Add(accessExpirationTime)
 
-	refresh, err := generateJWT(ui, refreshExp)
+	refresh, err := s.generateJWT(ui, auth.TypeRefresh, refreshExp)
 	if err != nil {
 		return nil, err
 	}
 
-	access, err := generateJWT(ui, accessExp)
+	access, err := s.generateJWT(ui, auth.TypeAccess, accessExp)
 	if err != nil {
 		return nil, err
 	}
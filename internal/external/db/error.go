@@ -8,5 +8,12 @@ var (
 )
 
 const (
-	CodeUniqueConstraint = "23505"
+	CodeUniqueConstraint     = "23505"
+	CodeForeignKeyConstraint = "23503"
+	CodeCheckConstraint      = "23514"
+)
+
+// constraint names for telling unique violations apart
+const (
+	ConstraintUserEmail = "tc_user_email_key"
 )
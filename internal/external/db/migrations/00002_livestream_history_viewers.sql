@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tc_livestream_history
+    ADD COLUMN peak_viewers INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN avg_viewers INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS tc_livestream_history_user_started_idx
+    ON tc_livestream_history (id_user, started_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS tc_livestream_history_user_started_idx;
+
+ALTER TABLE tc_livestream_history
+    DROP COLUMN IF EXISTS peak_viewers,
+    DROP COLUMN IF EXISTS avg_viewers;
+-- +goose StatementEnd
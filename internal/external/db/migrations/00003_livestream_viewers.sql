@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- viewer samples collected by the updater. id_livestream is not a foreign key
+-- because ended livestreams are moved to tc_livestream_history under the same id
+CREATE TABLE IF NOT EXISTS tc_livestream_viewers(
+    id_livestream INTEGER NOT NULL,
+    sampled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    viewers INTEGER NOT NULL,
+
+    PRIMARY KEY (id_livestream, sampled_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tc_livestream_viewers CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE password_algo_enum AS ENUM ('plain', 'bcrypt');
+
+-- passwords stored before hashing was introduced are raw, they are rehashed on the next sign in
+ALTER TABLE tc_user ADD COLUMN IF NOT EXISTS password_algo password_algo_enum NOT NULL DEFAULT 'plain';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tc_user DROP COLUMN IF EXISTS password_algo;
+
+DROP TYPE IF EXISTS password_algo_enum CASCADE;
+-- +goose StatementEnd
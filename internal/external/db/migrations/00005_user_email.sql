@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- users signed up before emails were collected have none
+ALTER TABLE tc_user ADD COLUMN IF NOT EXISTS email VARCHAR(254) DEFAULT NULL;
+ALTER TABLE tc_user ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS tc_user_email_key ON tc_user (lower(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS tc_user_email_key;
+
+ALTER TABLE tc_user DROP COLUMN IF EXISTS email_verified;
+ALTER TABLE tc_user DROP COLUMN IF EXISTS email;
+-- +goose StatementEnd
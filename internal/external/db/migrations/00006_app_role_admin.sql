@@ -0,0 +1,16 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- enum values can't be added inside a transaction block
+ALTER TYPE app_role_enum ADD VALUE IF NOT EXISTS 'admin';
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE tc_user SET app_role = 'staff' WHERE app_role = 'admin';
+
+ALTER TABLE tc_user ALTER COLUMN app_role DROP DEFAULT;
+ALTER TYPE app_role_enum RENAME TO app_role_enum_old;
+CREATE TYPE app_role_enum AS ENUM ('user', 'staff');
+ALTER TABLE tc_user ALTER COLUMN app_role TYPE app_role_enum USING app_role::text::app_role_enum;
+ALTER TABLE tc_user ALTER COLUMN app_role SET DEFAULT 'user';
+DROP TYPE app_role_enum_old;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- users appointed by the channel owner to moderate the channel. channel is the owner's tc_user row
+CREATE TABLE IF NOT EXISTS tc_channel_moderator(
+    id_channel INTEGER NOT NULL,
+    id_user INTEGER NOT NULL,
+    added_by INTEGER,
+    added_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (id_channel, id_user),
+    CHECK (id_channel <> id_user),
+    FOREIGN KEY (id_channel) REFERENCES tc_user (id) ON DELETE CASCADE,
+    FOREIGN KEY (id_user) REFERENCES tc_user (id) ON DELETE CASCADE,
+    FOREIGN KEY (added_by) REFERENCES tc_user (id) ON DELETE SET NULL
+);
+
+-- channels moderated by the user
+CREATE INDEX IF NOT EXISTS tc_channel_moderator_user_idx ON tc_channel_moderator (id_user);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tc_channel_moderator CASCADE;
+-- +goose StatementEnd
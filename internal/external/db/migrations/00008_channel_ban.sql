@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- bans are timeouts when expires_at is set, expired rows are removed by the ban cleanup task
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from DROP DEFAULT;
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from TYPE TIMESTAMP WITH TIME ZONE USING banned_from::timestamptz;
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from SET DEFAULT now();
+UPDATE tc_user_banned SET banned_from = now() WHERE banned_from IS NULL;
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from SET NOT NULL;
+
+ALTER TABLE tc_user_banned ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tc_user_banned ADD COLUMN IF NOT EXISTS banned_by INTEGER REFERENCES tc_user (id) ON DELETE SET NULL;
+ALTER TABLE tc_user_banned ADD COLUMN IF NOT EXISTS reason VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS tc_user_banned_channel_idx ON tc_user_banned (id_channel, banned_from);
+CREATE INDEX IF NOT EXISTS tc_user_banned_expires_idx ON tc_user_banned (expires_at) WHERE expires_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS tc_user_banned_expires_idx;
+DROP INDEX IF EXISTS tc_user_banned_channel_idx;
+
+ALTER TABLE tc_user_banned DROP COLUMN IF EXISTS reason;
+ALTER TABLE tc_user_banned DROP COLUMN IF EXISTS banned_by;
+ALTER TABLE tc_user_banned DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from DROP NOT NULL;
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from DROP DEFAULT;
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from TYPE DATE USING banned_from::date;
+ALTER TABLE tc_user_banned ALTER COLUMN banned_from SET DEFAULT CURRENT_DATE;
+-- +goose StatementEnd
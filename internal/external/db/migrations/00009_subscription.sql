@@ -0,0 +1,72 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 00001 created tc_user_subscriber twice with conflicting columns (only the first one took effect)
+-- and tiers not bound to a channel. nothing used either, so both are recreated
+DROP TABLE IF EXISTS tc_user_subscriber CASCADE;
+DROP TABLE IF EXISTS tc_subscription_tier CASCADE;
+
+CREATE TABLE IF NOT EXISTS tc_subscription_tier(
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    id_channel INTEGER NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    -- monthly price
+    price_cents INTEGER NOT NULL CHECK (price_cents > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (id),
+    UNIQUE (id_channel, name),
+    FOREIGN KEY (id_channel) REFERENCES tc_user (id) ON DELETE CASCADE
+);
+
+-- subscription is active until period_end. auto_renew is cleared by cancelling,
+-- the renewal task charges renewing subscriptions and removes the rest once the period is over
+CREATE TABLE IF NOT EXISTS tc_user_subscriber(
+    id_user INTEGER NOT NULL,
+    id_channel INTEGER NOT NULL,
+    id_tier INTEGER NOT NULL,
+    subscribed_from TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- paid periods in a row
+    months INTEGER NOT NULL DEFAULT 1,
+    auto_renew BOOLEAN NOT NULL DEFAULT true,
+    -- id of the last payment on the provider side
+    payment_id VARCHAR(128),
+
+    PRIMARY KEY (id_user, id_channel),
+    CHECK (id_user <> id_channel),
+    FOREIGN KEY (id_user) REFERENCES tc_user (id) ON DELETE CASCADE,
+    FOREIGN KEY (id_channel) REFERENCES tc_user (id) ON DELETE CASCADE,
+    FOREIGN KEY (id_tier) REFERENCES tc_subscription_tier (id)
+);
+
+CREATE INDEX IF NOT EXISTS tc_user_subscriber_period_end_idx ON tc_user_subscriber (period_end);
+CREATE INDEX IF NOT EXISTS tc_user_subscriber_tier_idx ON tc_user_subscriber (id_tier);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tc_user_subscriber CASCADE;
+DROP TABLE IF EXISTS tc_subscription_tier CASCADE;
+
+CREATE TABLE IF NOT EXISTS tc_subscription_tier(
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    name VARCHAR(32) NOT NULL,
+    cost DECIMAL(2) NOT NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS tc_user_subscriber(
+    id_user INTEGER NOT NULL,
+    id_subscriber INTEGER NOT NULL,
+    first_subscription DATE DEFAULT NULL,
+    last_subscription DATE DEFAULT NULL,
+    subscription_count INTEGER DEFAULT NULL,
+    id_subscription_tier INTEGER DEFAULT NULL,
+
+    UNIQUE (id_user, id_subscriber),
+    FOREIGN KEY (id_user) REFERENCES tc_user (id),
+    FOREIGN KEY (id_subscriber) REFERENCES tc_user (id),
+    FOREIGN KEY (id_subscription_tier) REFERENCES tc_subscription_tier (id)
+);
+-- +goose StatementEnd
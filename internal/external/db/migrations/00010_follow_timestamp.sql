@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- following_from orders followers pages, so it needs more than a day of precision
+ALTER TABLE tc_user_follow ALTER COLUMN following_from DROP DEFAULT;
+ALTER TABLE tc_user_follow ALTER COLUMN following_from TYPE TIMESTAMP WITH TIME ZONE USING following_from::timestamptz;
+ALTER TABLE tc_user_follow ALTER COLUMN following_from SET DEFAULT now();
+UPDATE tc_user_follow SET following_from = now() WHERE following_from IS NULL;
+ALTER TABLE tc_user_follow ALTER COLUMN following_from SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tc_user_follow_followers_idx ON tc_user_follow (id_follow, following_from DESC, id_user DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS tc_user_follow_followers_idx;
+
+ALTER TABLE tc_user_follow ALTER COLUMN following_from DROP NOT NULL;
+ALTER TABLE tc_user_follow ALTER COLUMN following_from DROP DEFAULT;
+ALTER TABLE tc_user_follow ALTER COLUMN following_from TYPE DATE USING following_from::date;
+ALTER TABLE tc_user_follow ALTER COLUMN following_from SET DEFAULT CURRENT_DATE;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+DELETE FROM tc_user_follow WHERE id_user = id_follow;
+ALTER TABLE tc_user_follow ADD CONSTRAINT tc_user_follow_not_self CHECK (id_user <> id_follow);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tc_user_follow DROP CONSTRAINT IF EXISTS tc_user_follow_not_self;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- cheaper tier chosen while subscribed, it replaces id_tier on the next renewal
+ALTER TABLE tc_user_subscriber ADD COLUMN IF NOT EXISTS id_tier_next INTEGER REFERENCES tc_subscription_tier (id) ON DELETE SET NULL;
+-- set by the renewal task while it charges the subscription, so other instances skip it
+-- without a lock being held during the charge. claims older than 5 minutes are abandoned
+ALTER TABLE tc_user_subscriber ADD COLUMN IF NOT EXISTS renew_claimed_at TIMESTAMP WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tc_user_subscriber DROP COLUMN IF EXISTS renew_claimed_at;
+ALTER TABLE tc_user_subscriber DROP COLUMN IF EXISTS id_tier_next;
+-- +goose StatementEnd
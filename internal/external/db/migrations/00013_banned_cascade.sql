@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- bans go away with the user or the channel
+ALTER TABLE tc_user_banned DROP CONSTRAINT IF EXISTS tc_user_banned_id_user_fkey;
+ALTER TABLE tc_user_banned DROP CONSTRAINT IF EXISTS tc_user_banned_id_channel_fkey;
+ALTER TABLE tc_user_banned ADD CONSTRAINT tc_user_banned_id_user_fkey FOREIGN KEY (id_user) REFERENCES tc_user (id) ON DELETE CASCADE;
+ALTER TABLE tc_user_banned ADD CONSTRAINT tc_user_banned_id_channel_fkey FOREIGN KEY (id_channel) REFERENCES tc_user (id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tc_user_banned DROP CONSTRAINT IF EXISTS tc_user_banned_id_channel_fkey;
+ALTER TABLE tc_user_banned DROP CONSTRAINT IF EXISTS tc_user_banned_id_user_fkey;
+ALTER TABLE tc_user_banned ADD CONSTRAINT tc_user_banned_id_user_fkey FOREIGN KEY (id_user) REFERENCES tc_user (id);
+ALTER TABLE tc_user_banned ADD CONSTRAINT tc_user_banned_id_channel_fkey FOREIGN KEY (id_channel) REFERENCES tc_user (id);
+-- +goose StatementEnd
@@ -16,6 +16,7 @@ type AppRoleEnum string
 const (
 	AppRoleEnumUser  AppRoleEnum = "user"
 	AppRoleEnumStaff AppRoleEnum = "staff"
+	AppRoleEnumAdmin AppRoleEnum = "admin"
 )
 
 func (e *AppRoleEnum) Scan(src interface{}) error {
@@ -97,6 +98,48 @@ func (ns NullChatEventEnum) Value() (driver.Value, error) {
 	return string(ns.ChatEventEnum), nil
 }
 
+type PasswordAlgoEnum string
+
+const (
+	PasswordAlgoEnumPlain  PasswordAlgoEnum = "plain"
+	PasswordAlgoEnumBcrypt PasswordAlgoEnum = "bcrypt"
+)
+
+func (e *PasswordAlgoEnum) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PasswordAlgoEnum(s)
+	case string:
+		*e = PasswordAlgoEnum(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PasswordAlgoEnum: %T", src)
+	}
+	return nil
+}
+
+type NullPasswordAlgoEnum struct {
+	PasswordAlgoEnum PasswordAlgoEnum
+	Valid            bool // Valid is true if PasswordAlgoEnum is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPasswordAlgoEnum) Scan(value interface{}) error {
+	if value == nil {
+		ns.PasswordAlgoEnum, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PasswordAlgoEnum.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPasswordAlgoEnum) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PasswordAlgoEnum), nil
+}
+
 type TcCategory struct {
 	ID        int32
 	Name      string
@@ -112,6 +155,13 @@ type TcCategoryTag struct {
 	IDTag      int32
 }
 
+type TcChannelModerator struct {
+	IDChannel int32
+	IDUser    int32
+	AddedBy   pgtype.Int4
+	AddedAt   pgtype.Timestamptz
+}
+
 type TcLivestream struct {
 	ID            int32
 	IDUser        int32
@@ -122,19 +172,29 @@ type TcLivestream struct {
 	IsMultistream bool
 }
 
+type TcLivestreamViewer struct {
+	IDLivestream int32
+	SampledAt    pgtype.Timestamptz
+	Viewers      int32
+}
+
 type TcLivestreamHistory struct {
-	ID         int32
-	IDUser     int32
-	IDCategory int32
-	Title      pgtype.Text
-	StartedAt  pgtype.Timestamptz
-	EndedAt    pgtype.Timestamptz
+	ID          int32
+	IDUser      int32
+	IDCategory  int32
+	Title       pgtype.Text
+	StartedAt   pgtype.Timestamptz
+	EndedAt     pgtype.Timestamptz
+	PeakViewers int32
+	AvgViewers  int32
 }
 
 type TcSubscriptionTier struct {
-	ID   int32
-	Name string
-	Cost pgtype.Numeric
+	ID         int32
+	IDChannel  int32
+	Name       string
+	PriceCents int32
+	CreatedAt  pgtype.Timestamptz
 }
 
 type TcTag struct {
@@ -162,6 +222,18 @@ type TcUser struct {
 	AppRole           AppRoleEnum
 	IDCategory        pgtype.Int4
 	Title             pgtype.Text
+	PasswordAlgo      PasswordAlgoEnum
+	Email             pgtype.Text
+	EmailVerified     bool
+}
+
+type TcUserBanned struct {
+	IDUser     int32
+	IDChannel  int32
+	BannedFrom pgtype.Timestamptz
+	ExpiresAt  pgtype.Timestamptz
+	BannedBy   pgtype.Int4
+	Reason     pgtype.Text
 }
 
 type TcUserChatEvent struct {
@@ -177,14 +249,18 @@ type TcUserChatEvent struct {
 type TcUserFollow struct {
 	IDUser        int32
 	IDFollow      int32
-	FollowingFrom pgtype.Date
+	FollowingFrom pgtype.Timestamptz
 }
 
 type TcUserSubscriber struct {
-	IDUser             int32
-	IDSubscriber       int32
-	FirstSubscription  pgtype.Date
-	LastSubscription   pgtype.Date
-	SubscriptionCount  pgtype.Int4
-	IDSubscriptionTier pgtype.Int4
+	IDUser         int32
+	IDChannel      int32
+	IDTier         int32
+	SubscribedFrom pgtype.Timestamptz
+	PeriodEnd      pgtype.Timestamptz
+	Months         int32
+	AutoRenew      bool
+	PaymentID      pgtype.Text
+	IDTierNext     pgtype.Int4
+	RenewClaimedAt pgtype.Timestamptz
 }
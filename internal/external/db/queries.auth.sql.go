@@ -7,48 +7,116 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const authInsertUser = `-- name: AuthInsertUser :exec
-INSERT INTO
-    tc_user(name, password)
-VALUES
-    ($1, $2)
+const authSelectEmail = `-- name: AuthSelectEmail :one
+SELECT
+    email,
+    email_verified
+FROM
+    tc_user
+WHERE
+    id = $1
 `
 
-type AuthInsertUserParams struct {
-	Name     string
-	Password string
+type AuthSelectEmailRow struct {
+	Email         pgtype.Text
+	EmailVerified bool
 }
 
-func (q *Queries) AuthInsertUser(ctx context.Context, arg AuthInsertUserParams) error {
-	_, err := q.db.Exec(ctx, authInsertUser, arg.Name, arg.Password)
-	return err
+func (q *Queries) AuthSelectEmail(ctx context.Context, id int32) (AuthSelectEmailRow, error) {
+	row := q.db.QueryRow(ctx, authSelectEmail, id)
+	var i AuthSelectEmailRow
+	err := row.Scan(&i.Email, &i.EmailVerified)
+	return i, err
 }
 
 const authSelectUser = `-- name: AuthSelectUser :one
 SELECT
     id,
-    app_role
+    app_role,
+    password,
+    password_algo
 FROM
     tc_user
 WHERE
-    name = $1 AND password = $2
+    name = $1
 `
 
-type AuthSelectUserParams struct {
-	Name     string
-	Password string
+type AuthSelectUserRow struct {
+	ID           int32
+	AppRole      AppRoleEnum
+	Password     string
+	PasswordAlgo PasswordAlgoEnum
 }
 
-type AuthSelectUserRow struct {
+func (q *Queries) AuthSelectUser(ctx context.Context, name string) (AuthSelectUserRow, error) {
+	row := q.db.QueryRow(ctx, authSelectUser, name)
+	var i AuthSelectUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.AppRole,
+		&i.Password,
+		&i.PasswordAlgo,
+	)
+	return i, err
+}
+
+const authSelectUserByName = `-- name: AuthSelectUserByName :one
+SELECT
+    id,
+    app_role
+FROM
+    tc_user
+WHERE
+    name = $1
+`
+
+type AuthSelectUserByNameRow struct {
 	ID      int32
 	AppRole AppRoleEnum
 }
 
-func (q *Queries) AuthSelectUser(ctx context.Context, arg AuthSelectUserParams) (AuthSelectUserRow, error) {
-	row := q.db.QueryRow(ctx, authSelectUser, arg.Name, arg.Password)
-	var i AuthSelectUserRow
+func (q *Queries) AuthSelectUserByName(ctx context.Context, name string) (AuthSelectUserByNameRow, error) {
+	row := q.db.QueryRow(ctx, authSelectUserByName, name)
+	var i AuthSelectUserByNameRow
 	err := row.Scan(&i.ID, &i.AppRole)
 	return i, err
 }
+
+const authUpdatePassword = `-- name: AuthUpdatePassword :exec
+UPDATE
+    tc_user
+SET
+    password = $2,
+    password_algo = $3
+WHERE
+    id = $1
+`
+
+type AuthUpdatePasswordParams struct {
+	ID           int32
+	Password     string
+	PasswordAlgo PasswordAlgoEnum
+}
+
+func (q *Queries) AuthUpdatePassword(ctx context.Context, arg AuthUpdatePasswordParams) error {
+	_, err := q.db.Exec(ctx, authUpdatePassword, arg.ID, arg.Password, arg.PasswordAlgo)
+	return err
+}
+
+const authVerifyEmail = `-- name: AuthVerifyEmail :exec
+UPDATE
+    tc_user
+SET
+    email_verified = TRUE
+WHERE
+    id = $1
+`
+
+func (q *Queries) AuthVerifyEmail(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, authVerifyEmail, id)
+	return err
+}
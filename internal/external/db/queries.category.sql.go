@@ -178,6 +178,66 @@ func (q *Queries) CategorySelect(ctx context.Context, id int32) (TcCategory, err
 	return i, err
 }
 
+const categorySelectAll = `-- name: CategorySelectAll :many
+SELECT
+    c.id AS category_id,
+    c.name AS category_name,
+    c.link AS category_link,
+    is_safe,
+    viewers,
+    image,
+    t.id AS tag_id,
+    t.name AS tag_name
+FROM
+    tc_category c
+LEFT OUTER JOIN
+    tc_category_tag ct ON ct.id_category = c.id
+LEFT OUTER JOIN
+    tc_tag t ON t.id = ct.id_tag
+ORDER BY
+    c.id
+`
+
+type CategorySelectAllRow struct {
+	CategoryID   int32
+	CategoryName string
+	CategoryLink string
+	IsSafe       bool
+	Viewers      int32
+	Image        string
+	TagID        pgtype.Int4
+	TagName      pgtype.Text
+}
+
+func (q *Queries) CategorySelectAll(ctx context.Context) ([]CategorySelectAllRow, error) {
+	rows, err := q.db.Query(ctx, categorySelectAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CategorySelectAllRow
+	for rows.Next() {
+		var i CategorySelectAllRow
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.CategoryLink,
+			&i.IsSafe,
+			&i.Viewers,
+			&i.Image,
+			&i.TagID,
+			&i.TagName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const categorySelectMany = `-- name: CategorySelectMany :many
 SELECT
     c.id AS category_id,
@@ -337,3 +397,23 @@ func (q *Queries) CategoryUpdateByLink(ctx context.Context, arg CategoryUpdateBy
 	)
 	return i, err
 }
+
+const categoryUpdateViewersMany = `-- name: CategoryUpdateViewersMany :exec
+UPDATE tc_category AS c
+SET
+    viewers = v.viewers
+FROM
+    (SELECT unnest($1::int[]) AS id, unnest($2::int[]) AS viewers) AS v
+WHERE
+    c.id = v.id AND c.viewers <> v.viewers
+`
+
+type CategoryUpdateViewersManyParams struct {
+	Ids     []int32
+	Viewers []int32
+}
+
+func (q *Queries) CategoryUpdateViewersMany(ctx context.Context, arg CategoryUpdateViewersManyParams) error {
+	_, err := q.db.Exec(ctx, categoryUpdateViewersMany, arg.Ids, arg.Viewers)
+	return err
+}
@@ -62,3 +62,19 @@ func (q *Queries) ChannelSelect(ctx context.Context, name string) (ChannelSelect
 	)
 	return i, err
 }
+
+const channelSelectId = `-- name: ChannelSelectId :one
+SELECT
+    id
+FROM
+    tc_user
+WHERE
+    name = $1
+`
+
+func (q *Queries) ChannelSelectId(ctx context.Context, name string) (int32, error) {
+	row := q.db.QueryRow(ctx, channelSelectId, name)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
@@ -11,43 +11,60 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const followDelete = `-- name: FollowDelete :exec
+const followCount = `-- name: FollowCount :one
+SELECT
+    COUNT(*)
+FROM
+    tc_user_follow
+WHERE
+    id_follow = $1
+`
+
+func (q *Queries) FollowCount(ctx context.Context, idFollow int32) (int64, error) {
+	row := q.db.QueryRow(ctx, followCount, idFollow)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const followDelete = `-- name: FollowDelete :execrows
 DELETE FROM
-    tc_user_follow f
-USING
-    tc_user u1, tc_user u2
+    tc_user_follow
 WHERE
-    f.id_user = u1.id
-AND f.id_follow = u2.id
-AND u1.name = $1
-AND u2.name = $2
+    id_user = $1
+AND id_follow = $2
 `
 
 type FollowDeleteParams struct {
-	Name   string
-	Name_2 string
+	IDUser   int32
+	IDFollow int32
 }
 
-func (q *Queries) FollowDelete(ctx context.Context, arg FollowDeleteParams) error {
-	_, err := q.db.Exec(ctx, followDelete, arg.Name, arg.Name_2)
-	return err
+func (q *Queries) FollowDelete(ctx context.Context, arg FollowDeleteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, followDelete, arg.IDUser, arg.IDFollow)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const followInsert = `-- name: FollowInsert :exec
+const followInsert = `-- name: FollowInsert :execrows
 INSERT INTO tc_user_follow(id_user, id_follow)
-SELECT
-  (SELECT id FROM tc_user WHERE name = $1),
-  (SELECT id FROM tc_user WHERE name = $2)
+VALUES ($1, $2)
+ON CONFLICT (id_user, id_follow) DO NOTHING
 `
 
 type FollowInsertParams struct {
-	Column1 pgtype.Text
-	Column2 pgtype.Text
+	IDUser   int32
+	IDFollow int32
 }
 
-func (q *Queries) FollowInsert(ctx context.Context, arg FollowInsertParams) error {
-	_, err := q.db.Exec(ctx, followInsert, arg.Column1, arg.Column2)
-	return err
+func (q *Queries) FollowInsert(ctx context.Context, arg FollowInsertParams) (int64, error) {
+	result, err := q.db.Exec(ctx, followInsert, arg.IDUser, arg.IDFollow)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const followSelect = `-- name: FollowSelect :one
@@ -90,6 +107,132 @@ func (q *Queries) FollowSelect(ctx context.Context, arg FollowSelectParams) (Fol
 	return i, err
 }
 
+const followSelectBanned = `-- name: FollowSelectBanned :one
+SELECT EXISTS (
+    SELECT
+        1
+    FROM
+        tc_user_banned b
+    INNER JOIN
+        tc_user u ON u.id = b.id_user
+    INNER JOIN
+        tc_user c ON c.id = b.id_channel
+    WHERE
+        u.name = $1 AND c.name = $2
+    AND (b.expires_at IS NULL OR b.expires_at > now())
+)
+`
+
+type FollowSelectBannedParams struct {
+	Name   string
+	Name_2 string
+}
+
+func (q *Queries) FollowSelectBanned(ctx context.Context, arg FollowSelectBannedParams) (bool, error) {
+	row := q.db.QueryRow(ctx, followSelectBanned, arg.Name, arg.Name_2)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const followSelectChannels = `-- name: FollowSelectChannels :many
+SELECT
+    id_follow
+FROM
+    tc_user_follow
+WHERE
+    id_user = $1
+AND id_follow = ANY($2::int[])
+`
+
+type FollowSelectChannelsParams struct {
+	IDUser  int32
+	Column2 []int32
+}
+
+func (q *Queries) FollowSelectChannels(ctx context.Context, arg FollowSelectChannelsParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, followSelectChannels, arg.IDUser, arg.Column2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id_follow int32
+		if err := rows.Scan(&id_follow); err != nil {
+			return nil, err
+		}
+		items = append(items, id_follow)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const followSelectFollowers = `-- name: FollowSelectFollowers :many
+SELECT
+    u.id,
+    u.name,
+    u.pfp,
+    f.following_from
+FROM
+    tc_user_follow f
+INNER JOIN
+    tc_user u ON u.id = f.id_user
+WHERE
+    f.id_follow = $1
+AND ($2::timestamptz IS NULL OR (f.following_from, f.id_user) < ($2::timestamptz, $3::int))
+ORDER BY
+    f.following_from DESC,
+    f.id_user DESC
+LIMIT $4
+`
+
+type FollowSelectFollowersParams struct {
+	IDFollow int32
+	Column2  pgtype.Timestamptz
+	Column3  int32
+	Limit    int32
+}
+
+type FollowSelectFollowersRow struct {
+	ID            int32
+	Name          string
+	Pfp           pgtype.Text
+	FollowingFrom pgtype.Timestamptz
+}
+
+func (q *Queries) FollowSelectFollowers(ctx context.Context, arg FollowSelectFollowersParams) ([]FollowSelectFollowersRow, error) {
+	rows, err := q.db.Query(ctx, followSelectFollowers,
+		arg.IDFollow,
+		arg.Column2,
+		arg.Column3,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FollowSelectFollowersRow
+	for rows.Next() {
+		var i FollowSelectFollowersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Pfp,
+			&i.FollowingFrom,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const followSelectMany = `-- name: FollowSelectMany :many
 SELECT
     u1.name,
@@ -133,61 +276,83 @@ func (q *Queries) FollowSelectMany(ctx context.Context, name string) ([]FollowSe
 	return items, nil
 }
 
-const followSelectManyExtended = `-- name: FollowSelectManyExtended :many
+const followSelectManyNames = `-- name: FollowSelectManyNames :many
 SELECT
-    u.name AS username,
-    f.name AS following,
-    f.is_live,
-    f.pfp AS pfp,
-    ls.viewers,
-    ls.title,
-    c.name AS category
+    f.name
 FROM
     tc_user_follow uf
-JOIN
+INNER JOIN
     tc_user u ON uf.id_user = u.id
-LEFT JOIN
+INNER JOIN
     tc_user f ON uf.id_follow = f.id
-LEFT JOIN
-    tc_livestream ls ON uf.id_follow = ls.id_user
-LEFT JOIN
-    tc_category c ON ls.id_category = c.id
 WHERE
     u.name = $1
+`
+
+func (q *Queries) FollowSelectManyNames(ctx context.Context, name string) ([]string, error) {
+	rows, err := q.db.Query(ctx, followSelectManyNames, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const followSelectManyOffline = `-- name: FollowSelectManyOffline :many
+SELECT
+    f.name AS following,
+    f.pfp
+FROM
+    tc_user_follow uf
+INNER JOIN
+    tc_user u ON uf.id_user = u.id
+INNER JOIN
+    tc_user f ON uf.id_follow = f.id
+WHERE
+    u.name = $1 AND NOT (f.name = ANY($2::text[]))
 ORDER BY
-    f.is_live DESC,
-    ls.viewers DESC
-LIMIT 50
+    f.name
+LIMIT $3 OFFSET $4
 `
 
-type FollowSelectManyExtendedRow struct {
-	Username  string
-	Following pgtype.Text
-	IsLive    pgtype.Bool
+type FollowSelectManyOfflineParams struct {
+	Name    string
+	Column2 []string
+	Limit   int32
+	Offset  int32
+}
+
+type FollowSelectManyOfflineRow struct {
+	Following string
 	Pfp       pgtype.Text
-	Viewers   pgtype.Int4
-	Title     pgtype.Text
-	Category  pgtype.Text
 }
 
-func (q *Queries) FollowSelectManyExtended(ctx context.Context, name string) ([]FollowSelectManyExtendedRow, error) {
-	rows, err := q.db.Query(ctx, followSelectManyExtended, name)
+func (q *Queries) FollowSelectManyOffline(ctx context.Context, arg FollowSelectManyOfflineParams) ([]FollowSelectManyOfflineRow, error) {
+	rows, err := q.db.Query(ctx, followSelectManyOffline,
+		arg.Name,
+		arg.Column2,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []FollowSelectManyExtendedRow
+	var items []FollowSelectManyOfflineRow
 	for rows.Next() {
-		var i FollowSelectManyExtendedRow
-		if err := rows.Scan(
-			&i.Username,
-			&i.Following,
-			&i.IsLive,
-			&i.Pfp,
-			&i.Viewers,
-			&i.Title,
-			&i.Category,
-		); err != nil {
+		var i FollowSelectManyOfflineRow
+		if err := rows.Scan(&i.Following, &i.Pfp); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -11,16 +11,83 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const livestreamDelete = `-- name: LivestreamDelete :exec
+const livestreamDelete = `-- name: LivestreamDelete :one
 DELETE FROM
     tc_livestream l
 WHERE
     l.id = $1
+RETURNING id, id_user, id_category, viewers, title, started_at, is_multistream
 `
 
-func (q *Queries) LivestreamDelete(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, livestreamDelete, id)
-	return err
+func (q *Queries) LivestreamDelete(ctx context.Context, id int32) (TcLivestream, error) {
+	row := q.db.QueryRow(ctx, livestreamDelete, id)
+	var i TcLivestream
+	err := row.Scan(
+		&i.ID,
+		&i.IDUser,
+		&i.IDCategory,
+		&i.Viewers,
+		&i.Title,
+		&i.StartedAt,
+		&i.IsMultistream,
+	)
+	return i, err
+}
+
+const livestreamHistoryInsert = `-- name: LivestreamHistoryInsert :one
+INSERT INTO tc_livestream_history (
+    id,
+    id_user,
+    id_category,
+    title,
+    started_at,
+    peak_viewers,
+    avg_viewers
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING id, id_user, id_category, title, started_at, ended_at, peak_viewers, avg_viewers
+`
+
+type LivestreamHistoryInsertParams struct {
+	ID          int32
+	IDUser      int32
+	IDCategory  int32
+	Title       pgtype.Text
+	StartedAt   pgtype.Timestamptz
+	PeakViewers int32
+	AvgViewers  int32
+}
+
+func (q *Queries) LivestreamHistoryInsert(ctx context.Context, arg LivestreamHistoryInsertParams) (TcLivestreamHistory, error) {
+	row := q.db.QueryRow(ctx, livestreamHistoryInsert,
+		arg.ID,
+		arg.IDUser,
+		arg.IDCategory,
+		arg.Title,
+		arg.StartedAt,
+		arg.PeakViewers,
+		arg.AvgViewers,
+	)
+	var i TcLivestreamHistory
+	err := row.Scan(
+		&i.ID,
+		&i.IDUser,
+		&i.IDCategory,
+		&i.Title,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.PeakViewers,
+		&i.AvgViewers,
+	)
+	return i, err
 }
 
 const livestreamInsert = `-- name: LivestreamInsert :one
@@ -90,6 +157,74 @@ func (q *Queries) LivestreamInsert(ctx context.Context, name string) (Livestream
 	return i, err
 }
 
+const livestreamSelectAll = `-- name: LivestreamSelectAll :many
+SELECT
+    ls.id AS livestream_id,
+    u.id AS user_id,
+    u.pfp AS user_pfp,
+    u.name AS user_name,
+    ls.title AS title,
+    c.id AS category_id,
+    c.link AS category_link,
+    c.name AS category_name,
+    ls.started_at,
+    ls.viewers
+FROM
+    tc_livestream ls
+JOIN
+    tc_user u
+ON
+    ls.id_user = u.id
+JOIN
+    tc_category c
+ON
+    ls.id_category = c.id
+`
+
+type LivestreamSelectAllRow struct {
+	LivestreamID int32
+	UserID       int32
+	UserPfp      pgtype.Text
+	UserName     string
+	Title        pgtype.Text
+	CategoryID   int32
+	CategoryLink string
+	CategoryName string
+	StartedAt    pgtype.Timestamptz
+	Viewers      int32
+}
+
+func (q *Queries) LivestreamSelectAll(ctx context.Context) ([]LivestreamSelectAllRow, error) {
+	rows, err := q.db.Query(ctx, livestreamSelectAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LivestreamSelectAllRow
+	for rows.Next() {
+		var i LivestreamSelectAllRow
+		if err := rows.Scan(
+			&i.LivestreamID,
+			&i.UserID,
+			&i.UserPfp,
+			&i.UserName,
+			&i.Title,
+			&i.CategoryID,
+			&i.CategoryLink,
+			&i.CategoryName,
+			&i.StartedAt,
+			&i.Viewers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const livestreamUpdate = `-- name: LivestreamUpdate :one
 WITH updated AS (
     UPDATE tc_livestream ls
@@ -160,20 +295,50 @@ func (q *Queries) LivestreamUpdate(ctx context.Context, arg LivestreamUpdatePara
 	return i, err
 }
 
-const livestreamUpdateViewers = `-- name: LivestreamUpdateViewers :exec
-UPDATE tc_livestream
+const livestreamUpdateViewersMany = `-- name: LivestreamUpdateViewersMany :exec
+UPDATE tc_livestream AS ls
     SET
-        viewers = $2
+        viewers = v.viewers
+    FROM
+        (SELECT unnest($1::int[]) AS id, unnest($2::int[]) AS viewers) AS v
+    WHERE
+        ls.id = v.id AND ls.viewers <> v.viewers
+`
+
+type LivestreamUpdateViewersManyParams struct {
+	Ids     []int32
+	Viewers []int32
+}
+
+func (q *Queries) LivestreamUpdateViewersMany(ctx context.Context, arg LivestreamUpdateViewersManyParams) error {
+	_, err := q.db.Exec(ctx, livestreamUpdateViewersMany, arg.Ids, arg.Viewers)
+	return err
+}
+
+const livestreamUserSetLive = `-- name: LivestreamUserSetLive :exec
+UPDATE tc_user
+    SET
+        is_live = TRUE,
+        first_livestream = COALESCE(first_livestream, CURRENT_DATE)
     WHERE
         id = $1
 `
 
-type LivestreamUpdateViewersParams struct {
-	ID      int32
-	Viewers int32
+func (q *Queries) LivestreamUserSetLive(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, livestreamUserSetLive, id)
+	return err
 }
 
-func (q *Queries) LivestreamUpdateViewers(ctx context.Context, arg LivestreamUpdateViewersParams) error {
-	_, err := q.db.Exec(ctx, livestreamUpdateViewers, arg.ID, arg.Viewers)
+const livestreamUserSetOffline = `-- name: LivestreamUserSetOffline :exec
+UPDATE tc_user
+    SET
+        is_live = FALSE,
+        last_livestream = CURRENT_DATE
+    WHERE
+        id = $1
+`
+
+func (q *Queries) LivestreamUserSetOffline(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, livestreamUserSetOffline, id)
 	return err
 }
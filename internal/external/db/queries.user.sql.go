@@ -27,22 +27,34 @@ const userInsert = `-- name: UserInsert :one
 INSERT INTO tc_user (
     name,
     password,
+    password_algo,
+    email,
     pfp)
 VALUES (
     $1,
     $2,
-    $3)
+    $3,
+    $4,
+    $5)
 RETURNING id
 `
 
 type UserInsertParams struct {
-	Name     string
-	Password string
-	Pfp      pgtype.Text
+	Name         string
+	Password     string
+	PasswordAlgo PasswordAlgoEnum
+	Email        pgtype.Text
+	Pfp          pgtype.Text
 }
 
 func (q *Queries) UserInsert(ctx context.Context, arg UserInsertParams) (int32, error) {
-	row := q.db.QueryRow(ctx, userInsert, arg.Name, arg.Password, arg.Pfp)
+	row := q.db.QueryRow(ctx, userInsert,
+		arg.Name,
+		arg.Password,
+		arg.PasswordAlgo,
+		arg.Email,
+		arg.Pfp,
+	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
@@ -134,13 +146,14 @@ UPDATE
 SET
     name       = CASE WHEN $1::boolean THEN $2 ELSE name END,
     password   = CASE WHEN $3::boolean THEN $4 ELSE password END,
-    is_banned  = CASE WHEN $5::boolean THEN $6 ELSE is_banned END,
-    is_partner = CASE WHEN $7::boolean THEN $8 ELSE is_partner END,
-    pfp        = CASE WHEN $9::boolean THEN $10 ELSE pfp END,
+    password_algo = CASE WHEN $3::boolean THEN $5 ELSE password_algo END,
+    is_banned  = CASE WHEN $6::boolean THEN $7 ELSE is_banned END,
+    is_partner = CASE WHEN $8::boolean THEN $9 ELSE is_partner END,
+    pfp        = CASE WHEN $10::boolean THEN $11 ELSE pfp END,
     updated_at = CURRENT_DATE
 WHERE
-    id = $11
-RETURNING id, name, password, created_at, updated_at, is_banned, is_partner, first_livestream, last_livestream, stream_token, is_live, pfp, offline_background, description, links, tags, app_role, id_category, title
+    id = $12
+RETURNING id, name, password, created_at, updated_at, is_banned, is_partner, first_livestream, last_livestream, stream_token, is_live, pfp, offline_background, description, links, tags, app_role, id_category, title, password_algo, email, email_verified
 `
 
 type UserUpdateParams struct {
@@ -148,6 +161,7 @@ type UserUpdateParams struct {
 	Name              string
 	PasswordDoUpdate  bool
 	Password          string
+	PasswordAlgo      PasswordAlgoEnum
 	IsBannedDoUpdate  bool
 	IsBanned          pgtype.Bool
 	IsPartnerDoUpdate bool
@@ -163,6 +177,7 @@ func (q *Queries) UserUpdate(ctx context.Context, arg UserUpdateParams) error {
 		arg.Name,
 		arg.PasswordDoUpdate,
 		arg.Password,
+		arg.PasswordAlgo,
 		arg.IsBannedDoUpdate,
 		arg.IsBanned,
 		arg.IsPartnerDoUpdate,
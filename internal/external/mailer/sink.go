@@ -0,0 +1,81 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// logs messages instead of sending them. with non-empty path messages are also
+// appended to the file as json lines, so tests can read them back
+type Sink struct {
+	log  *slog.Logger
+	path string
+	mu   sync.Mutex
+}
+
+func NewSink(log *slog.Logger, path string) *Sink {
+	return &Sink{log: log, path: path}
+}
+
+func (s *Sink) Send(ctx context.Context, msg Message) error {
+	s.log.Info("mail sent",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body))
+
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint
+
+	return json.NewEncoder(f).Encode(msg)
+}
+
+// latest message sent to the address
+func (s *Sink) Last(to string) (*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint
+
+	var last *Message
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var msg Message
+		err := json.Unmarshal(sc.Bytes(), &msg)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.To == to {
+			last = &msg
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if last == nil {
+		return nil, errors.New("no messages")
+	}
+
+	return last, nil
+}
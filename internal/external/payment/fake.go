@@ -0,0 +1,75 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// accepts every charge except the ones of declined customers. nothing leaves the process,
+// so it is used in development and tests
+type Fake struct {
+	mu       sync.Mutex
+	charges  []Charge
+	ids      map[string]string
+	refunded map[string]bool
+	declined map[int32]bool
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		ids:      make(map[string]string),
+		refunded: make(map[string]bool),
+		declined: make(map[int32]bool),
+	}
+}
+
+func (f *Fake) Charge(ctx context.Context, ch Charge) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.declined[ch.CustomerID] {
+		return "", ErrDeclined
+	}
+
+	if id, ok := f.ids[ch.Reference]; ok {
+		return id, nil
+	}
+
+	id := fmt.Sprintf("fake_%d", len(f.charges)+1)
+	f.ids[ch.Reference] = id
+	f.charges = append(f.charges, ch)
+
+	return id, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, paymentId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.refunded[paymentId] = true
+	return nil
+}
+
+// makes every following charge of the customer fail
+func (f *Fake) Decline(customerID int32, declined bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.declined[customerID] = declined
+}
+
+// successful charges of the customer that weren't refunded, oldest first
+func (f *Fake) Charges(customerID int32) []Charge {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var res []Charge
+	for _, ch := range f.charges {
+		if ch.CustomerID == customerID && !f.refunded[f.ids[ch.Reference]] {
+			res = append(res, ch)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,24 @@
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrDeclined = errors.New("payment declined")
+
+type Charge struct {
+	// idempotency key, charging the same reference twice charges once
+	Reference   string
+	CustomerID  int32
+	AmountCents int
+	Description string
+}
+
+// charges subscribers. implementations are expected to be safe for concurrent use
+type Provider interface {
+	// returns id of the payment on the provider side
+	Charge(ctx context.Context, ch Charge) (string, error)
+	// returns the money of a successful charge
+	Refund(ctx context.Context, paymentId string) error
+}
@@ -1,24 +1,38 @@
 package streamserver
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	// snapshots are small frames, anything bigger is not a snapshot
+	maxSnapshotSize = 5 << 20
 )
 
 type Adapter struct {
 	endpoint string
+	// url of the latest frame of a stream, "{channel}" is replaced with channel name
+	snapshotURL string
+	cl          *http.Client
 }
 
-func NewAdapter(endpoint string) *Adapter {
-	return &Adapter{endpoint: endpoint + "streams"}
+func NewAdapter(endpoint string, snapshotURL string) *Adapter {
+	return &Adapter{
+		endpoint:    endpoint + "streams",
+		snapshotURL: snapshotURL,
+		cl:          &http.Client{Timeout: requestTimeout}}
 }
 
 func (u *Adapter) List(ctx context.Context, start, count int) (*ListResponse, error) {
-	cl := &http.Client{}
-	response, err := cl.Get(fmt.Sprintf("%s?start=%d&count=%d", u.endpoint, start, count))
+	response, err := u.cl.Get(fmt.Sprintf("%s?start=%d&count=%d", u.endpoint, start, count))
 	if err != nil {
 		return nil, err
 	}
@@ -38,8 +52,7 @@ func (u *Adapter) List(ctx context.Context, start, count int) (*ListResponse, er
 }
 
 func (u *Adapter) Get(ctx context.Context, channel string) (*GetResponse, error) {
-	cl := &http.Client{}
-	response, err := cl.Get(u.endpoint + "/" + channel)
+	response, err := u.cl.Get(u.endpoint + "/" + channel)
 	if err != nil {
 		return nil, err
 	}
@@ -55,5 +68,51 @@ func (u *Adapter) Get(ctx context.Context, channel string) (*GetResponse, error)
 		return nil, err
 	}
 
+	// srs responds with non-zero code and no stream if it is not published
+	if resp.Code != 0 || resp.Stream.Name == "" {
+		return nil, ErrStreamNotFound
+	}
+
 	return &resp, nil
 }
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// latest png frame of the stream produced by the stream server (srs snapshot transcode engine)
+func (u *Adapter) Snapshot(ctx context.Context, channel string) ([]byte, error) {
+	url := strings.ReplaceAll(u.snapshotURL, "{channel}", channel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := u.cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close() // nolint
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrSnapshotNotFound
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected snapshot response status: %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSnapshotSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > maxSnapshotSize {
+		return nil, fmt.Errorf("snapshot of %s exceeds %d bytes", channel, maxSnapshotSize)
+	}
+
+	if !bytes.HasPrefix(body, pngSignature) {
+		return nil, fmt.Errorf("snapshot of %s is not a png", channel)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,8 @@
+package streamserver
+
+import "errors"
+
+var (
+	ErrStreamNotFound   = errors.New("stream is not found on the stream server")
+	ErrSnapshotNotFound = errors.New("snapshot is not available on the stream server")
+)
@@ -28,7 +28,17 @@ func (c *StreamServerClient) Start(username string) (*http.Response, error) {
 }
 
 func (c *StreamServerClient) Stop(username string) (*http.Response, error) {
-	req, err := c.base.Delete(c.BaseURL+username, nil)
+	req, err := c.base.Delete(c.BaseURL+"/streams/"+username, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer req.Body.Close() // nolint
+
+	return c.base.Client.Do(req)
+}
+
+func (c *StreamServerClient) Subscribe(callbackURL string) (*http.Response, error) {
+	req, err := c.base.Post(c.BaseURL+"/subscribe", streamserver.SubscribeRequest{CallbackURL: callbackURL})
 	if err != nil {
 		return nil, err
 	}
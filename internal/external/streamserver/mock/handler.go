@@ -3,7 +3,13 @@ package mock
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
 	"net/http"
+	"strings"
 	"twitchy-api/internal/external/streamserver"
 	baseclient "twitchy-api/pkg/api/client"
 )
@@ -49,19 +55,44 @@ func (s *handler) Post(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(err)
 	}
 
-	for _, s := range s.state.subs {
-		cl := baseclient.NewClient()
-		cl.Post(s, streamserver.StreamEventPayload{
-			Action: "on_publish",
-		})
+	s.notify("on_publish", req.Channel)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	err := s.state.streams.End(r.Context(), id)
+	if err != nil {
+		fmt.Println(err)
 	}
 
+	s.notify("on_unpublish", id)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func Delete(w http.ResponseWriter, r *http.Request) {
-	// id := r.PathValue("id")
-	fmt.Println("delete not implemented")
+// sends srs-like http hook to every subscriber
+func (s *handler) notify(action, channel string) {
+	for _, sub := range s.state.subs {
+		req, err := s.state.cl.Post(sub, streamserver.StreamEventPayload{
+			Action: action,
+			App:    "live",
+			Stream: channel,
+		})
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		resp, err := s.state.cl.Client.Do(req)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		resp.Body.Close() // nolint
+	}
 }
 
 func (s *handler) List(w http.ResponseWriter, r *http.Request) {
@@ -81,3 +112,34 @@ func (s *handler) List(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(streamserver.ListResponse{Streams: streams})
 }
+
+// generates png "frame" of the stream: channel-specific background with some noise
+// so that consecutive snapshots differ like real ones do
+func (s *handler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	channel := strings.TrimSuffix(r.PathValue("file"), ".png")
+
+	_, err := s.state.streams.Get(r.Context(), channel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(channel)) // nolint
+	sum := h.Sum32()
+	bg := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 320, 180))
+	for x := range 320 {
+		for y := range 180 {
+			img.Set(x, y, bg)
+		}
+	}
+
+	for range 500 {
+		img.Set(rand.Intn(320), rand.Intn(180), color.White)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, img) // nolint
+}
@@ -49,8 +49,11 @@ func Run(ctx context.Context, log *slog.Logger) error {
 	apiMux.HandleFunc("GET /streams/{id}", handler.Get)
 	apiMux.HandleFunc("GET /streams", handler.List)
 	apiMux.HandleFunc("POST /streams", handler.Post)
-	apiMux.HandleFunc("DELETE /streams/{id}", Delete)
+	apiMux.HandleFunc("DELETE /streams/{id}", handler.Delete)
 	apiMux.HandleFunc("POST /subscribe", handler.Subscribe)
+	apiMux.HandleFunc("POST /unsubscribe", handler.Unsubscribe)
+	// {file} is "<channel>.png"
+	apiMux.HandleFunc("GET /snapshots/{file}", handler.Snapshot)
 
 	mainMux := http.NewServeMux()
 	mainMux.Handle(cfg.Endpoint+"/", http.StripPrefix(cfg.Endpoint, apiMux))
@@ -3,6 +3,12 @@ package follow
 import "errors"
 
 var (
-	ErrNoFollowed = errors.New("followed username is not present")
-	ErrNoFollower = errors.New("follower username is not present")
+	ErrNoFollowed   = errors.New("followed username is not present")
+	ErrNoFollower   = errors.New("follower username is not present")
+	ErrBanned       = errors.New("user is banned in the channel")
+	ErrNotFound     = errors.New("channel is not found")
+	ErrBadCursor    = errors.New("bad cursor parameter")
+	ErrUserNotFound = errors.New("user is not found")
+	ErrSelfFollow   = errors.New("user can't follow themselves")
+	ErrBadLive      = errors.New("bad live parameter: expected true or false")
 )
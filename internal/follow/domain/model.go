@@ -1,5 +1,14 @@
 package follow
 
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"twitchy-api/internal/lib/null"
+)
+
 type FollowingListExtendedItem struct {
 	Name     string
 	Pfp      string
@@ -9,7 +18,72 @@ type FollowingListExtendedItem struct {
 	IsLive   bool
 }
 
+// Live filters by live status when explicit, both live and offline channels are listed otherwise
+type FollowingSearch struct {
+	Follower string
+	Live     null.Bool
+	Page     int
+	Count    int
+}
+
 type FollowerListItem struct {
 	Name string
 	Pfp  string
 }
+
+// follower of a channel
+type Follower struct {
+	Id            int32
+	Name          string
+	Pfp           string
+	FollowingFrom time.Time
+}
+
+// followers are paged by a cursor instead of page numbers, so new follows don't shift the pages being read.
+// empty Cursor means the first page
+type FollowerSearch struct {
+	Channel string
+	Cursor  string
+	Count   int
+}
+
+type FollowerPage struct {
+	Followers []Follower
+	// empty when there are no more followers
+	NextCursor string
+}
+
+// position after which the next page starts, encoded as "<following_from unix micro>:<follower id>"
+type FollowerCursor struct {
+	FollowingFrom time.Time
+	Id            int32
+}
+
+func (c FollowerCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.FollowingFrom.UnixMicro(), c.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeFollowerCursor(s string) (FollowerCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return FollowerCursor{}, ErrBadCursor
+	}
+
+	micro, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return FollowerCursor{}, ErrBadCursor
+	}
+
+	microInt, err := strconv.ParseInt(micro, 10, 64)
+	if err != nil {
+		return FollowerCursor{}, ErrBadCursor
+	}
+
+	idInt, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		return FollowerCursor{}, ErrBadCursor
+	}
+
+	return FollowerCursor{FollowingFrom: time.UnixMicro(microInt), Id: int32(idInt)}, nil
+}
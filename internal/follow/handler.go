@@ -3,20 +3,24 @@ package follow
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"twitchy-api/internal/app/auth"
 	d "twitchy-api/internal/follow/domain"
 	"twitchy-api/internal/lib/handler"
+	"twitchy-api/internal/lib/null"
 	api "twitchy-api/pkg/api/follow"
 )
 
 type Repository interface {
 	IsFollower(ctx context.Context, follower, followed string) (bool, error)
 	List(ctx context.Context, follower string) ([]d.FollowerListItem, error)
-	ListExtended(ctx context.Context, follower string) ([]d.FollowingListExtendedItem, error)
+	ListExtended(ctx context.Context, s d.FollowingSearch) ([]d.FollowingListExtendedItem, error)
 	Follow(ctx context.Context, follower, followed string) error
 	Unfollow(ctx context.Context, unfollower, unfollowed string) error
+	Followers(ctx context.Context, search d.FollowerSearch) (*d.FollowerPage, error)
 }
 
 type Handler struct {
@@ -64,14 +68,18 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 // List godoc
 //
 //	@Summary		List followers or following
-//	@Description	Get list of users that follower is following (basic or extended)
+//	@Description	Get list of users that follower is following (basic or extended). Extended list has live channels first
+//	@Description	and is paginated, page, count and live apply only to it
 //	@Tags			Follows
 //	@Produce		json
 //	@Param			follower	query		string						true	"Username to get follow list for"
 //	@Param			extended	query		string						false	"true for extended info, false/default for basic"
+//	@Param			page		query		string						false	"Page number (default: 1)"
+//	@Param			count		query		string						false	"Items per page (default: 10, max: 100)"
+//	@Param			live		query		string						false	"true for live channels only, false for offline only"
 //	@Success		200			{object}	api.ListResponse			"Basic follow list"
 //	@Success		200			{object}	api.ListExtendedResponse	"Extended follow list"
-//	@Failure		400			{object}	handler.ErrorResponse		"Missing follower parameter"
+//	@Failure		400			{object}	handler.ErrorResponse		"Missing follower parameter or invalid page, count or live parameters"
 //	@Failure		500			{object}	handler.ErrorResponse		"Internal server error"
 //	@Router			/follows [get]
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +95,14 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 
 	extended := r.URL.Query().Get("extended")
 	if extended == "true" {
-		extendedList, err := h.r.ListExtended(ctx, follower)
+		search, errs := parseFollowingSearch(r)
+		if len(errs) != 0 {
+			handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+			return
+		}
+		search.Follower = follower
+
+		extendedList, err := h.r.ListExtended(ctx, search)
 		if err != nil {
 			handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
 			return
@@ -116,10 +131,78 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(api.ListResponse{FollowList: following})
 }
 
+// Followers godoc
+//
+//	@Summary		List channel followers
+//	@Description	Get followers of a channel, most recent first. Pages are chained by next_cursor
+//	@Tags			Follows
+//	@Produce		json
+//	@Param			channel	path		string					true	"Channel name"
+//	@Param			cursor	query		string					false	"next_cursor of the previous page"
+//	@Param			count	query		string					false	"Items per page (default: 20, max: 100)"
+//	@Success		200		{object}	api.FollowersResponse	"Followers page"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid cursor or count parameters"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel not found"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/followers [get]
+func (h *Handler) Followers(w http.ResponseWriter, r *http.Request) {
+	const op = "getting followers"
+
+	count := r.URL.Query().Get("count")
+	if count == "" {
+		count = "20"
+	}
+
+	countInt, err := strconv.Atoi(count)
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.ErrBadCount.Error())
+		return
+	}
+
+	if countInt < 1 {
+		countInt = 20
+	}
+
+	if countInt > 100 {
+		countInt = 100
+	}
+
+	page, err := h.r.Followers(r.Context(), d.FollowerSearch{
+		Channel: r.PathValue("channel"),
+		Cursor:  r.URL.Query().Get("cursor"),
+		Count:   countInt,
+	})
+	if err != nil {
+		if errors.Is(err, d.ErrBadCursor) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrBadCursor.Error())
+			return
+		}
+
+		if errors.Is(err, d.ErrNotFound) {
+			handler.Error(h.log, w, op, err, http.StatusNotFound, d.ErrNotFound.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	response := api.FollowersResponse{
+		Followers:  make([]api.FollowersResponseItem, len(page.Followers)),
+		NextCursor: page.NextCursor,
+	}
+
+	for i, f := range page.Followers {
+		response.Followers[i] = api.FollowersResponseItem(f)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
 // Post godoc
 //
-//	@Summary		Follow or unfollow user
-//	@Description	Follow a user (or unfollow if already following)
+//	@Summary		Follow user
+//	@Description	Follow a user. Following an already followed user does nothing
 //	@Tags			Follows
 //	@Accept			json
 //	@Security		BearerAuth
@@ -127,7 +210,9 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 //	@Param			request		body		api.PostRequest	true	"User to follow/unfollow"
 //	@Success		204			{object}	nil
 //	@Failure		401			{object}	handler.ErrorResponse	"Unauthorized - invalid claims or identity mismatch"
-//	@Failure		400			{object}	handler.ErrorResponse	"Invalid request"
+//	@Failure		400			{object}	handler.ErrorResponse	"Invalid request or following yourself"
+//	@Failure		403			{object}	handler.ErrorResponse	"Banned in the channel"
+//	@Failure		404			{object}	handler.ErrorResponse	"User not found"
 //	@Failure		500			{object}	handler.ErrorResponse	"Internal server error"
 //	@Router			/follows/{username} [post]
 func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
@@ -156,6 +241,21 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 
 	err = h.r.Follow(ctx, username, req.Follow)
 	if err != nil {
+		if errors.Is(err, d.ErrBanned) {
+			handler.Error(h.log, w, op, err, http.StatusForbidden, d.ErrBanned.Error())
+			return
+		}
+
+		if errors.Is(err, d.ErrUserNotFound) {
+			handler.Error(h.log, w, op, err, http.StatusNotFound, d.ErrUserNotFound.Error())
+			return
+		}
+
+		if errors.Is(err, d.ErrSelfFollow) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrSelfFollow.Error())
+			return
+		}
+
 		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
 		return
 	}
@@ -166,7 +266,7 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 // Delete godoc
 //
 //	@Summary		Unfollow user
-//	@Description	Unfollow a specific user
+//	@Description	Unfollow a specific user. Unfollowing a user who is not followed does nothing
 //	@Tags			Follows
 //	@Accept			json
 //	@Security		BearerAuth
@@ -175,6 +275,7 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 //	@Success		204			{object}	nil
 //	@Failure		401			{object}	handler.ErrorResponse	"Unauthorized - invalid claims or identity mismatch"
 //	@Failure		400			{object}	handler.ErrorResponse	"Invalid request"
+//	@Failure		404			{object}	handler.ErrorResponse	"User not found"
 //	@Failure		500			{object}	handler.ErrorResponse	"Internal server error"
 //	@Router			/follows/{username} [delete]
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -203,9 +304,66 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	err = h.r.Unfollow(ctx, username, req.Unfollow)
 	if err != nil {
+		if errors.Is(err, d.ErrUserNotFound) {
+			handler.Error(h.log, w, op, err, http.StatusNotFound, d.ErrUserNotFound.Error())
+			return
+		}
+
 		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func parseFollowingSearch(r *http.Request) (d.FollowingSearch, map[string]error) {
+	page := r.URL.Query().Get("page")
+	if page == "" {
+		page = "1"
+	}
+
+	errs := make(map[string]error)
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		errs["page"] = handler.ErrBadPage
+	}
+
+	if pageInt < 1 {
+		pageInt = 1
+	}
+
+	count := r.URL.Query().Get("count")
+	if count == "" {
+		count = "10"
+	}
+
+	countInt, err := strconv.Atoi(count)
+	if err != nil {
+		errs["count"] = handler.ErrBadCount
+	}
+
+	if countInt < 1 {
+		countInt = 10
+	}
+
+	if countInt > 100 {
+		countInt = 100
+	}
+
+	var live null.Bool
+	if l := r.URL.Query().Get("live"); l != "" {
+		value, err := strconv.ParseBool(l)
+		if err != nil {
+			errs["live"] = d.ErrBadLive
+		}
+
+		live = null.Bool{Value: value, Explicit: true}
+	}
+
+	return d.FollowingSearch{
+		Live:  live,
+		Page:  pageInt,
+		Count: countInt,
+	}, errs
+}
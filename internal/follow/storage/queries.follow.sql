@@ -23,23 +23,18 @@ WHERE
     u1.name = $1 AND u2.name = $2;
 
 
--- name: FollowInsert :exec
+-- name: FollowInsert :execrows
 INSERT INTO tc_user_follow(id_user, id_follow)
-SELECT
-  (SELECT id FROM tc_user WHERE name = $1),
-  (SELECT id FROM tc_user WHERE name = $2);
+VALUES ($1, $2)
+ON CONFLICT (id_user, id_follow) DO NOTHING;
 
 
--- name: FollowDelete :exec
+-- name: FollowDelete :execrows
 DELETE FROM
-    tc_user_follow f
-USING
-    tc_user u1, tc_user u2
+    tc_user_follow
 WHERE
-    f.id_user = u1.id
-AND f.id_follow = u2.id
-AND u1.name = $1
-AND u2.name = $2;
+    id_user = $1
+AND id_follow = $2;
 
 
 -- name: FollowSelectMany :many
@@ -60,28 +55,85 @@ WHERE
     u2.name = $1;
 
 
--- name: FollowSelectManyExtended :many
+-- name: FollowSelectManyNames :many
+SELECT
+    f.name
+FROM
+    tc_user_follow uf
+INNER JOIN
+    tc_user u ON uf.id_user = u.id
+INNER JOIN
+    tc_user f ON uf.id_follow = f.id
+WHERE
+    u.name = $1;
+
+
+-- name: FollowSelectManyOffline :many
 SELECT
-    u.name AS username,
     f.name AS following,
-    f.is_live,
-    f.pfp AS pfp,
-    ls.viewers,
-    ls.title,
-    c.name AS category
+    f.pfp
 FROM
     tc_user_follow uf
-JOIN
+INNER JOIN
     tc_user u ON uf.id_user = u.id
-LEFT JOIN
+INNER JOIN
     tc_user f ON uf.id_follow = f.id
-LEFT JOIN
-    tc_livestream ls ON uf.id_follow = ls.id_user
-LEFT JOIN
-    tc_category c ON ls.id_category = c.id
 WHERE
-    u.name = $1
+    u.name = $1 AND NOT (f.name = ANY($2::text[]))
 ORDER BY
-    f.is_live DESC,
-    ls.viewers DESC
-LIMIT 50;
+    f.name
+LIMIT $3 OFFSET $4;
+
+
+-- name: FollowSelectBanned :one
+SELECT EXISTS (
+    SELECT
+        1
+    FROM
+        tc_user_banned b
+    INNER JOIN
+        tc_user u ON u.id = b.id_user
+    INNER JOIN
+        tc_user c ON c.id = b.id_channel
+    WHERE
+        u.name = $1 AND c.name = $2
+    AND (b.expires_at IS NULL OR b.expires_at > now())
+);
+
+
+-- name: FollowSelectChannels :many
+SELECT
+    id_follow
+FROM
+    tc_user_follow
+WHERE
+    id_user = $1
+AND id_follow = ANY($2::int[]);
+
+
+-- name: FollowSelectFollowers :many
+SELECT
+    u.id,
+    u.name,
+    u.pfp,
+    f.following_from
+FROM
+    tc_user_follow f
+INNER JOIN
+    tc_user u ON u.id = f.id_user
+WHERE
+    f.id_follow = $1
+AND ($2::timestamptz IS NULL OR (f.following_from, f.id_user) < ($2::timestamptz, $3::int))
+ORDER BY
+    f.following_from DESC,
+    f.id_user DESC
+LIMIT $4;
+
+
+-- name: FollowCount :one
+SELECT
+    COUNT(*)
+FROM
+    tc_user_follow
+WHERE
+    id_follow = $1;
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"twitchy-api/internal/external/db"
+	d "twitchy-api/internal/follow/domain"
 
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -12,39 +13,64 @@ type queriesAdapter struct {
 	queries *db.Queries
 }
 
+func (q *queriesAdapter) Count(ctx context.Context, channelId int32) (int64, error) {
+	return q.queries.FollowCount(ctx, channelId)
+}
+
 func (q *queriesAdapter) Select(ctx context.Context, arg db.FollowSelectParams) (db.FollowSelectRow, error) {
 	return q.queries.FollowSelect(ctx, arg)
 }
 
+func (q *queriesAdapter) SelectBanned(ctx context.Context, arg db.FollowSelectBannedParams) (bool, error) {
+	return q.queries.FollowSelectBanned(ctx, arg)
+}
+
+func (q *queriesAdapter) SelectChannels(ctx context.Context, arg db.FollowSelectChannelsParams) ([]int32, error) {
+	return q.queries.FollowSelectChannels(ctx, arg)
+}
+
+func (q *queriesAdapter) SelectFollowers(ctx context.Context, arg db.FollowSelectFollowersParams) ([]db.FollowSelectFollowersRow, error) {
+	return q.queries.FollowSelectFollowers(ctx, arg)
+}
+
 func (q *queriesAdapter) SelectMany(ctx context.Context, name string) ([]db.FollowSelectManyRow, error) {
 	return q.queries.FollowSelectMany(ctx, name)
 }
 
-func (q *queriesAdapter) SelectManyExtended(ctx context.Context, name string) ([]db.FollowSelectManyExtendedRow, error) {
-	return q.queries.FollowSelectManyExtended(ctx, name)
+func (q *queriesAdapter) SelectManyNames(ctx context.Context, name string) ([]string, error) {
+	return q.queries.FollowSelectManyNames(ctx, name)
+}
+
+func (q *queriesAdapter) SelectManyOffline(ctx context.Context, arg db.FollowSelectManyOfflineParams) ([]db.FollowSelectManyOfflineRow, error) {
+	return q.queries.FollowSelectManyOffline(ctx, arg)
 }
 
 func (q *queriesAdapter) SelectUserId(ctx context.Context, name string) (int32, error) {
 	return q.queries.FollowSelectUserId(ctx, name)
 }
 
-func (q *queriesAdapter) Insert(ctx context.Context, arg db.FollowInsertParams) error {
-	err := q.queries.FollowInsert(ctx, arg)
-
+// returns 0 if the user already follows the channel
+func (q *queriesAdapter) Insert(ctx context.Context, arg db.FollowInsertParams) (int64, error) {
+	n, err := q.queries.FollowInsert(ctx, arg)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
-			if pgErr.Code == db.CodeUniqueConstraint {
-				return nil
+			// either user was deleted after ids were looked up
+			if pgErr.Code == db.CodeForeignKeyConstraint {
+				return 0, d.ErrUserNotFound
+			}
+
+			if pgErr.Code == db.CodeCheckConstraint {
+				return 0, d.ErrSelfFollow
 			}
 		}
 
-		return err
+		return 0, err
 	}
 
-	return err
+	return n, nil
 }
 
-func (q *queriesAdapter) Delete(ctx context.Context, arg db.FollowDeleteParams) error {
+func (q *queriesAdapter) Delete(ctx context.Context, arg db.FollowDeleteParams) (int64, error) {
 	return q.queries.FollowDelete(ctx, arg)
 }
@@ -1,21 +1,34 @@
 package follow
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"twitchy-api/internal/external/db"
 	d "twitchy-api/internal/follow/domain"
+	livestreamDomain "twitchy-api/internal/livestream/domain"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
+// source of live status of followed channels
+type LiveLister interface {
+	ListByUsernames(ctx context.Context, usernames []string) (map[string]livestreamDomain.Livestream, error)
+}
+
 type RepositoryImpl struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	counts *countStore
+	live   LiveLister
 }
 
-func NewRepository(pool *pgxpool.Pool) *RepositoryImpl {
-	return &RepositoryImpl{pool: pool}
+func NewRepository(pool *pgxpool.Pool, rdb *redis.Client, live LiveLister) *RepositoryImpl {
+	return &RepositoryImpl{pool: pool, counts: &countStore{rdb: rdb}, live: live}
 }
 
 func (r *RepositoryImpl) IsFollower(ctx context.Context, follower, followed string) (bool, error) {
@@ -32,6 +45,26 @@ func (r *RepositoryImpl) IsFollower(ctx context.Context, follower, followed stri
 	return true, nil
 }
 
+// reports which of channelIds the user follows, in a single query
+func (r *RepositoryImpl) FollowedChannels(ctx context.Context, userId int32, channelIds []int32) (map[int32]bool, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+
+	ids, err := q.SelectChannels(ctx, db.FollowSelectChannelsParams{
+		IDUser:  userId,
+		Column2: channelIds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followed channels: %w", err)
+	}
+
+	followed := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		followed[id] = true
+	}
+
+	return followed, nil
+}
+
 func (r *RepositoryImpl) List(ctx context.Context, follower string) ([]d.FollowerListItem, error) {
 	q := queriesAdapter{queries: db.New(r.pool)}
 
@@ -51,53 +84,296 @@ func (r *RepositoryImpl) List(ctx context.Context, follower string) ([]d.Followe
 	return following, nil
 }
 
-func (r *RepositoryImpl) ListExtended(ctx context.Context, follower string) ([]d.FollowingListExtendedItem, error) {
+// followed channels with their livestreams, live ones first by viewers, then offline ones by name.
+// channels are live while their livestream is in the cache, so followed channels are intersected
+// with it as a whole. offline channels are paged by the query and follow the live ones
+func (r *RepositoryImpl) ListExtended(ctx context.Context, s d.FollowingSearch) ([]d.FollowingListExtendedItem, error) {
 	q := queriesAdapter{queries: db.New(r.pool)}
 
-	list, err := q.SelectManyExtended(ctx, follower)
+	// offline channels are listed without the live ones, so they are needed either way
+	live, err := r.listLive(ctx, q, s.Follower)
+	if err != nil {
+		return nil, err
+	}
+
+	start := (s.Page - 1) * s.Count
+
+	if s.Live.Explicit && !s.Live.Value {
+		return r.listOffline(ctx, q, s.Follower, live, s.Count, start)
+	}
+
+	following := make([]d.FollowingListExtendedItem, 0, s.Count)
+	if start < len(live) {
+		following = append(following, live[start:min(start+s.Count, len(live))]...)
+	}
+
+	if (s.Live.Explicit && s.Live.Value) || len(following) == s.Count {
+		return following, nil
+	}
+
+	offline, err := r.listOffline(ctx, q, s.Follower, live, s.Count-len(following), max(start-len(live), 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(following, offline...), nil
+}
+
+// live followed channels by viewers, then by name
+func (r *RepositoryImpl) listLive(ctx context.Context, q queriesAdapter, follower string) ([]d.FollowingListExtendedItem, error) {
+	names, err := q.SelectManyNames(ctx, follower)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followed channels: %w", err)
+	}
+
+	livestreams, err := r.live.ListByUsernames(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get livestreams of followed channels: %w", err)
+	}
+
+	live := make([]d.FollowingListExtendedItem, 0, len(livestreams))
+	for _, ls := range livestreams {
+		live = append(live, d.FollowingListExtendedItem{
+			Name:     ls.UserName,
+			Pfp:      ls.UserPfp,
+			IsLive:   true,
+			Viewers:  ls.Viewers,
+			Title:    ls.Title,
+			Category: ls.CategoryName,
+		})
+	}
+
+	slices.SortFunc(live, func(a, b d.FollowingListExtendedItem) int {
+		return cmp.Or(cmp.Compare(b.Viewers, a.Viewers), cmp.Compare(a.Name, b.Name))
+	})
+
+	return live, nil
+}
+
+// page of followed channels by name, except the live ones
+func (r *RepositoryImpl) listOffline(ctx context.Context,
+	q queriesAdapter,
+	follower string,
+	live []d.FollowingListExtendedItem,
+	limit, offset int) ([]d.FollowingListExtendedItem, error) {
+	// not nil, the query would match nothing otherwise
+	liveNames := make([]string, len(live))
+	for i, l := range live {
+		liveNames[i] = l.Name
+	}
+
+	list, err := q.SelectManyOffline(ctx, db.FollowSelectManyOfflineParams{
+		Name:    follower,
+		Column2: liveNames,
+		Limit:   int32(limit),
+		Offset:  int32(offset)})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get extended following list: %w", err)
+		return nil, fmt.Errorf("failed to get offline followed channels: %w", err)
 	}
 
-	following := make([]d.FollowingListExtendedItem, len(list))
+	offline := make([]d.FollowingListExtendedItem, len(list))
 	for i, f := range list {
-		following[i] = d.FollowingListExtendedItem{
-			Name:     f.Following.String,
-			Pfp:      f.Pfp.String,
-			Viewers:  int(f.Viewers.Int32),
-			Title:    f.Title.String,
-			Category: f.Category.String,
-			IsLive:   f.IsLive.Bool,
+		offline[i] = d.FollowingListExtendedItem{
+			Name: f.Following,
+			Pfp:  f.Pfp.String,
 		}
 	}
 
-	return following, nil
+	return offline, nil
 }
 
+// following the same user again is a no-op
 func (r *RepositoryImpl) Follow(ctx context.Context, follower, followed string) error {
 	q := queriesAdapter{queries: db.New(r.pool)}
 
-	err := q.Insert(ctx, db.FollowInsertParams{
-		Column1: pgtype.Text{String: follower, Valid: true},
-		Column2: pgtype.Text{String: followed, Valid: true},
+	followerId, followedId, err := r.followIds(ctx, q, follower, followed)
+	if err != nil {
+		return err
+	}
+
+	if followerId == followedId {
+		return d.ErrSelfFollow
+	}
+
+	banned, err := q.SelectBanned(ctx, db.FollowSelectBannedParams{
+		Name:   follower,
+		Name_2: followed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to determine whether user is banned: %w", err)
+	}
+
+	if banned {
+		return d.ErrBanned
+	}
+
+	inserted, err := q.Insert(ctx, db.FollowInsertParams{
+		IDUser:   followerId,
+		IDFollow: followedId,
 	})
 	if err != nil {
+		if errors.Is(err, d.ErrUserNotFound) || errors.Is(err, d.ErrSelfFollow) {
+			return err
+		}
+
 		return fmt.Errorf("failed to follow: %w", err)
 	}
 
+	if inserted == 0 {
+		return nil
+	}
+
+	if err := r.counts.incr(ctx, followedId, 1); err != nil {
+		return fmt.Errorf("failed to update follower count: %w", err)
+	}
+
 	return nil
 }
 
+// unfollowing a user who is not followed is a no-op
 func (r *RepositoryImpl) Unfollow(ctx context.Context, unfollower, unfollowed string) error {
 	q := queriesAdapter{queries: db.New(r.pool)}
 
-	err := q.Delete(ctx, db.FollowDeleteParams{
-		Name:   unfollower,
-		Name_2: unfollowed,
+	unfollowerId, unfollowedId, err := r.followIds(ctx, q, unfollower, unfollowed)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := q.Delete(ctx, db.FollowDeleteParams{
+		IDUser:   unfollowerId,
+		IDFollow: unfollowedId,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to unfollow: %w", err)
 	}
 
+	if deleted == 0 {
+		return nil
+	}
+
+	if err := r.counts.incr(ctx, unfollowedId, -1); err != nil {
+		return fmt.Errorf("failed to update follower count: %w", err)
+	}
+
 	return nil
 }
+
+func (r *RepositoryImpl) Followers(ctx context.Context, search d.FollowerSearch) (*d.FollowerPage, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+
+	params := db.FollowSelectFollowersParams{
+		// one more row tells whether there is a next page
+		Limit: int32(search.Count) + 1,
+	}
+
+	if search.Cursor != "" {
+		cursor, err := d.DecodeFollowerCursor(search.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		params.Column2 = pgtype.Timestamptz{Time: cursor.FollowingFrom, Valid: true}
+		params.Column3 = cursor.Id
+	}
+
+	channelId, err := r.channelId(ctx, q, search.Channel)
+	if err != nil {
+		return nil, err
+	}
+	params.IDFollow = channelId
+
+	rows, err := q.SelectFollowers(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers: %w", err)
+	}
+
+	page := &d.FollowerPage{}
+	if len(rows) > search.Count {
+		rows = rows[:search.Count]
+		last := rows[len(rows)-1]
+		page.NextCursor = d.FollowerCursor{FollowingFrom: last.FollowingFrom.Time, Id: last.ID}.Encode()
+	}
+
+	page.Followers = make([]d.Follower, len(rows))
+	for i, f := range rows {
+		page.Followers[i] = d.Follower{
+			Id:            f.ID,
+			Name:          f.Name,
+			Pfp:           f.Pfp.String,
+			FollowingFrom: f.FollowingFrom.Time,
+		}
+	}
+
+	return page, nil
+}
+
+// changes the cached follower count of the channel, for follows removed outside of Unfollow
+func (r *RepositoryImpl) AdjustFollowerCount(ctx context.Context, channelId int32, delta int64) error {
+	return r.counts.incr(ctx, channelId, delta)
+}
+
+// number of followers of the channel, cached in redis
+func (r *RepositoryImpl) FollowerCount(ctx context.Context, channel string) (int64, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+
+	channelId, err := r.channelId(ctx, q, channel)
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok, err := r.counts.get(ctx, channelId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cached follower count: %w", err)
+	}
+
+	if ok {
+		return count, nil
+	}
+
+	count, err = q.Count(ctx, channelId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count followers: %w", err)
+	}
+
+	if err := r.counts.set(ctx, channelId, count); err != nil {
+		return 0, fmt.Errorf("failed to cache follower count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *RepositoryImpl) channelId(ctx context.Context, q queriesAdapter, channel string) (int32, error) {
+	id, err := q.SelectUserId(ctx, channel)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, d.ErrNotFound
+		}
+
+		return 0, fmt.Errorf("failed to get channel id: %w", err)
+	}
+
+	return id, nil
+}
+
+// ids of the follower and the followed user
+func (r *RepositoryImpl) followIds(ctx context.Context, q queriesAdapter, follower, followed string) (int32, int32, error) {
+	followerId, err := q.SelectUserId(ctx, follower)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, d.ErrUserNotFound
+		}
+
+		return 0, 0, fmt.Errorf("failed to get follower id: %w", err)
+	}
+
+	followedId, err := q.SelectUserId(ctx, followed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, d.ErrUserNotFound
+		}
+
+		return 0, 0, fmt.Errorf("failed to get followed user id: %w", err)
+	}
+
+	return followerId, followedId, nil
+}
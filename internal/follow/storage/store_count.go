@@ -0,0 +1,56 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// follower counts of channels, so reading them doesn't need COUNT(*). a missing key is filled from
+// the database on read, and the ttl makes counts that drifted (e.g. failed update) heal by themselves
+//
+// keys are "follower_count:<channel id>"
+type countStore struct {
+	rdb *redis.Client
+}
+
+const countTTL = 24 * time.Hour
+
+// changes the count only when it is cached. incrementing a missing key would start it from zero
+// and hide the followers that are in the database
+var incrScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+return 1
+`)
+
+// returns false if the count is not cached
+func (c *countStore) get(ctx context.Context, channelId int32) (int64, bool, error) {
+	count, err := c.rdb.Get(ctx, c.key(channelId)).Int64()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return count, true, nil
+}
+
+// caches the count unless it was cached in the meantime
+func (c *countStore) set(ctx context.Context, channelId int32, count int64) error {
+	return c.rdb.SetNX(ctx, c.key(channelId), count, countTTL).Err()
+}
+
+func (c *countStore) incr(ctx context.Context, channelId int32, delta int64) error {
+	return incrScript.Run(ctx, c.rdb, []string{c.key(channelId)}, delta).Err()
+}
+
+func (c *countStore) key(channelId int32) string {
+	return fmt.Sprintf("follower_count:%d", channelId)
+}
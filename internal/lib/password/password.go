@@ -0,0 +1,75 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// algorithms of tc_user.password, match password_algo_enum
+const (
+	// raw password, left from before passwords were hashed
+	AlgoPlain  = "plain"
+	AlgoBcrypt = "bcrypt"
+)
+
+// algorithm of hashes produced by Hash
+const AlgoCurrent = AlgoBcrypt
+
+// passwords with fewer characters are too weak
+const MinLength = 8
+
+var ErrTooLong = errors.New("password is too long")
+
+func Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", ErrTooLong
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// compares password with the stored hash
+func Verify(hash, algo, password string) (bool, error) {
+	switch algo {
+	case AlgoBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	case AlgoPlain:
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1, nil
+	default:
+		return false, fmt.Errorf("unknown password algorithm %q", algo)
+	}
+}
+
+// whether the hash should be replaced with the one produced by Hash
+func NeedsRehash(hash, algo string) bool {
+	if algo != AlgoCurrent {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < bcrypt.DefaultCost
+}
+
+// spends as much time as verifying a real hash, so unknown users can't be told apart by response time
+func VerifyDummy(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
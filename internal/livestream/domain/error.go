@@ -3,8 +3,12 @@ package domain
 import "errors"
 
 var (
-	ErrAlreadyStarted = errors.New("livestream already started")
-	ErrAlreadyEnded   = errors.New("livestream already ended")
-	ErrNotFound       = errors.New("livestream is not found")
-	ErrNoCategory     = errors.New("neither category nor category id is present")
+	ErrAlreadyStarted    = errors.New("livestream already started")
+	ErrAlreadyEnded      = errors.New("livestream already ended")
+	ErrNotFound          = errors.New("livestream is not found")
+	ErrNoCategory        = errors.New("neither category nor category id is present")
+	ErrNoChannel         = errors.New("channel is not present in the event")
+	ErrWebhookSecret     = errors.New("invalid webhook secret")
+	ErrThumbnailNotFound = errors.New("thumbnail is not found")
+	ErrBadResolution     = errors.New("bad resolution parameter: expected duration from 1m to 24h (e.g. 1m, 5m, 1h)")
 )
@@ -3,6 +3,7 @@ package domain
 import (
 	"encoding/json"
 	"strconv"
+	"time"
 	"twitchy-api/internal/lib/null"
 	api "twitchy-api/pkg/api/livestream"
 )
@@ -19,6 +20,19 @@ type Livestream struct {
 	CategoryId   int    `redis:"category:id"`
 	CategoryName string `redis:"category:name"`
 	CategoryLink string `redis:"category:link"`
+	// viewer samples collected by the updater, used to archive peak/average viewers
+	PeakViewers    int `redis:"peak_viewers"`
+	ViewersSum     int `redis:"viewers_sum"`
+	ViewersSamples int `redis:"viewers_samples"`
+}
+
+// average of collected viewer samples, 0 if there are none
+func (l *Livestream) AvgViewers() int {
+	if l.ViewersSamples == 0 {
+		return 0
+	}
+
+	return l.ViewersSum / l.ViewersSamples
 }
 
 func (l *Livestream) ToGetResponse() api.GetResponse {
@@ -65,6 +79,57 @@ func (l *Livestream) ToListResponseItem() api.ListResponseItem {
 	}
 }
 
+// ended livestream from tc_livestream_history
+type Broadcast struct {
+	Id           int
+	Title        string
+	StartedAt    time.Time
+	EndedAt      time.Time
+	PeakViewers  int
+	AvgViewers   int
+	CategoryId   int
+	CategoryName string
+	CategoryLink string
+}
+
+func (b *Broadcast) ToListResponseItem() api.BroadcastListResponseItem {
+	return api.BroadcastListResponseItem{
+		Id:    b.Id,
+		Title: b.Title,
+		Category: api.LivestreamCategory{
+			Id:   b.CategoryId,
+			Name: b.CategoryName,
+			Link: b.CategoryLink,
+		},
+		StartedAt:   int(b.StartedAt.Unix()),
+		EndedAt:     int(b.EndedAt.Unix()),
+		Duration:    int(b.EndedAt.Sub(b.StartedAt).Seconds()),
+		PeakViewers: b.PeakViewers,
+		AvgViewers:  b.AvgViewers,
+	}
+}
+
+// viewers of a livestream aggregated over a time bucket starting at Time
+type ViewersSample struct {
+	Time        time.Time
+	PeakViewers int
+	AvgViewers  int
+}
+
+func (s *ViewersSample) ToResponseItem() api.ViewersResponseItem {
+	return api.ViewersResponseItem{
+		Time:        int(s.Time.Unix()),
+		PeakViewers: s.PeakViewers,
+		AvgViewers:  s.AvgViewers,
+	}
+}
+
+type BroadcastSearch struct {
+	Channel string
+	Page    int
+	Count   int
+}
+
 type LivestreamUpdate struct {
 	Title      null.String
 	CategoryId null.Int
@@ -7,7 +7,10 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+	"twitchy-api/internal/app/auth"
 	"twitchy-api/internal/lib/handler"
+	"twitchy-api/internal/lib/sl"
 	d "twitchy-api/internal/livestream/domain"
 	api "twitchy-api/pkg/api/livestream"
 )
@@ -21,18 +24,38 @@ type Lister interface {
 	List(ctx context.Context, s d.LivestreamSearch) ([]d.Livestream, error)
 }
 
+type BroadcastLister interface {
+	ListBroadcasts(ctx context.Context, s d.BroadcastSearch) ([]d.Broadcast, error)
+}
+
+type ViewersLister interface {
+	ListViewers(ctx context.Context, id int, resolution time.Duration) ([]d.ViewersSample, error)
+}
+
 type GetterLister interface {
 	Getter
 	Lister
+	BroadcastLister
+	ViewersLister
+}
+
+type FollowChecker interface {
+	FollowedChannels(ctx context.Context, userId int32, channelIds []int32) (map[int32]bool, error)
+}
+
+type SubscriptionChecker interface {
+	SubscribedChannels(ctx context.Context, userId int32, channelIds []int32) (map[int32]bool, error)
 }
 
 type Handler struct {
-	r   GetterLister
-	log *slog.Logger
+	r       GetterLister
+	follows FollowChecker
+	subs    SubscriptionChecker
+	log     *slog.Logger
 }
 
-func NewHandler(log *slog.Logger, r GetterLister) *Handler {
-	return &Handler{r: r, log: log}
+func NewHandler(log *slog.Logger, r GetterLister, follows FollowChecker, subs SubscriptionChecker) *Handler {
+	return &Handler{r: r, follows: follows, subs: subs, log: log}
 }
 
 // Get godoc
@@ -69,6 +92,63 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ls.ToGetResponse()
+	h.personalize(r.Context(), ls, &response)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Viewers godoc
+//
+//	@Summary		Get livestream viewers graph
+//	@Description	Get viewer samples of a running or ended livestream aggregated by resolution
+//	@Tags			Livestreams
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Livestream ID"
+//	@Param			resolution	query		string	false	"Bucket size from 1m to 24h (default: 1m)"
+//	@Success		200			{object}	ViewersResponse
+//	@Failure		400			{object}	ErrorResponse	"Invalid ID or resolution"
+//	@Failure		500			{object}	ErrorResponse	"Internal server error"
+//	@Router			/livestreams/{id}/viewers [get]
+func (h *Handler) Viewers(w http.ResponseWriter, r *http.Request) {
+	const op = "getting livestream viewers"
+
+	errs := make(map[string]error)
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		errs["id"] = err
+	}
+
+	resolution := r.URL.Query().Get("resolution")
+	if resolution == "" {
+		resolution = "1m"
+	}
+
+	res, err := time.ParseDuration(resolution)
+	if err != nil || res < time.Minute || res > 24*time.Hour {
+		errs["resolution"] = d.ErrBadResolution
+	}
+
+	if len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	samples, err := h.r.ListViewers(r.Context(), idInt, res)
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	response := api.ViewersResponse{
+		Resolution: res.String(),
+		Samples:    make([]api.ViewersResponseItem, len(samples)),
+	}
+
+	for i, s := range samples {
+		response.Samples[i] = s.ToResponseItem()
+	}
+
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -99,6 +179,7 @@ func (h *Handler) GetByUsername(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ls.ToGetResponse()
+	h.personalize(r.Context(), ls, &response)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -173,9 +254,125 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		Livestreams: make([]api.ListResponseItem, len(livestreams)),
 	}
 
+	channelIds := make([]int32, len(livestreams))
+	for i, ls := range livestreams {
+		channelIds[i] = int32(ls.UserId)
+	}
+
+	following, subscribed := h.viewerStatus(r.Context(), channelIds)
 	for i, ls := range livestreams {
 		listResponse.Livestreams[i] = ls.ToListResponseItem()
+		listResponse.Livestreams[i].IsFollowing = following[int32(ls.UserId)]
+		listResponse.Livestreams[i].IsSubscriber = subscribed[int32(ls.UserId)]
 	}
 
 	json.NewEncoder(w).Encode(listResponse)
 }
+
+// Broadcasts godoc
+//
+//	@Summary		List past broadcasts
+//	@Description	Get paginated list of ended livestreams of a channel, most recent first
+//	@Tags			Livestreams
+//	@Accept			json
+//	@Produce		json
+//	@Param			channel	path		string	true	"Channel name"
+//	@Param			page	query		string	false	"Page number (default: 1)"
+//	@Param			count	query		string	false	"Items per page (default: 10, max: 100)"
+//	@Success		200		{object}	BroadcastListResponse
+//	@Failure		400		{object}	ErrorResponse	"Invalid page or count parameters"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/broadcasts [get]
+func (h *Handler) Broadcasts(w http.ResponseWriter, r *http.Request) {
+	const op = "getting broadcasts"
+
+	page := r.URL.Query().Get("page")
+	if page == "" {
+		page = "1"
+	}
+
+	errs := make(map[string]error)
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		errs["page"] = handler.ErrBadPage
+	}
+
+	if pageInt < 1 {
+		pageInt = 1
+	}
+
+	count := r.URL.Query().Get("count")
+	if count == "" {
+		count = "10"
+	}
+
+	countInt, err := strconv.Atoi(count)
+	if err != nil {
+		errs["count"] = handler.ErrBadCount
+	}
+
+	if len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	if countInt < 1 {
+		countInt = 10
+	}
+
+	if countInt > 100 {
+		countInt = 100
+	}
+
+	broadcasts, err := h.r.ListBroadcasts(r.Context(), d.BroadcastSearch{
+		Channel: r.PathValue("channel"),
+		Page:    pageInt,
+		Count:   countInt,
+	})
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	response := api.BroadcastListResponse{
+		Broadcasts: make([]api.BroadcastListResponseItem, len(broadcasts)),
+	}
+
+	for i, b := range broadcasts {
+		response.Broadcasts[i] = b.ToListResponseItem()
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// fills viewer specific fields when the request is authenticated
+func (h *Handler) personalize(ctx context.Context, ls *d.Livestream, response *api.GetResponse) {
+	following, subscribed := h.viewerStatus(ctx, []int32{int32(ls.UserId)})
+	response.IsFollowing = following[int32(ls.UserId)]
+	response.IsSubscriber = subscribed[int32(ls.UserId)]
+}
+
+// returns which of the channels the authenticated user follows and is subscribed to, one query each
+// regardless of how many channels there are. maps are nil for anonymous requests. failures are logged
+// and leave the map nil, the livestreams themselves are still served
+func (h *Handler) viewerStatus(ctx context.Context, channelIds []int32) (following, subscribed map[int32]bool) {
+	const op = "personalizing livestreams"
+
+	user, ok := auth.FromContext(ctx)
+	if !ok || len(channelIds) == 0 {
+		return nil, nil
+	}
+
+	following, err := h.follows.FollowedChannels(ctx, user.Id, channelIds)
+	if err != nil {
+		h.log.Error("getting follow status", sl.Err(err), sl.Op(op))
+	}
+
+	subscribed, err = h.subs.SubscribedChannels(ctx, user.Id, channelIds)
+	if err != nil {
+		h.log.Error("getting subscription status", sl.Err(err), sl.Op(op))
+	}
+
+	return following, subscribed
+}
@@ -0,0 +1,92 @@
+package livestream
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"twitchy-api/internal/lib/sl"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type viewersSyncer interface {
+	SyncViewers(ctx context.Context) (int, error)
+}
+
+type viewersFlusher interface {
+	viewersSyncer
+	FlushViewers(ctx context.Context) (int, error)
+}
+
+// periodically writes viewers from the cache to pg:
+// buffered viewer samples and current viewers of livestreams and categories
+type ViewersFlusher struct {
+	log        *slog.Logger
+	rdb        *redis.Client
+	livestream viewersFlusher
+	categories viewersSyncer
+}
+
+func NewViewersFlusher(log *slog.Logger,
+	rdb *redis.Client,
+	livestream viewersFlusher,
+	categories viewersSyncer) *ViewersFlusher {
+	return &ViewersFlusher{log: log, rdb: rdb, livestream: livestream, categories: categories}
+}
+
+func (v *ViewersFlusher) Run(ctx context.Context, timeout time.Duration) error {
+	const op = "livestream.ViewersFlusher.Run"
+
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// write whatever is left before shutting down
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			v.flush(flushCtx, op)
+			cancel()
+
+			return nil
+		case <-ticker.C:
+			v.flush(ctx, op)
+			v.sync(ctx, op, timeout)
+		}
+	}
+}
+
+// samples are taken from the shared buffer atomically, so every instance flushes without a lock
+func (v *ViewersFlusher) flush(ctx context.Context, op string) {
+	n, err := v.livestream.FlushViewers(ctx)
+	if err != nil {
+		v.log.Error("flush viewers", sl.Err(err), sl.Op(op))
+		return
+	}
+
+	if n > 0 {
+		v.log.Debug("viewer samples flushed", slog.Int("count", n))
+	}
+}
+
+// current viewers are shared in the cache so only one instance per interval syncs them
+func (v *ViewersFlusher) sync(ctx context.Context, op string, timeout time.Duration) {
+	ok, err := v.rdb.SetNX(ctx, "viewers_sync_lock", 1, timeout).Result()
+	if err != nil || !ok {
+		return
+	}
+
+	n, err := v.livestream.SyncViewers(ctx)
+	if err != nil {
+		v.log.Error("sync livestream viewers", sl.Err(err), sl.Op(op))
+	} else {
+		v.log.Debug("livestream viewers synced", slog.Int("count", n))
+	}
+
+	n, err = v.categories.SyncViewers(ctx)
+	if err != nil {
+		v.log.Error("sync category viewers", sl.Err(err), sl.Op(op))
+	} else {
+		v.log.Debug("category viewers synced", slog.Int("count", n))
+	}
+}
@@ -0,0 +1,220 @@
+package livestream
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"time"
+	"twitchy-api/internal/external/streamserver"
+	"twitchy-api/internal/lib/sl"
+	d "twitchy-api/internal/livestream/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// drift counters, available at /debug/vars of the internal listener under "livestream_reconciler"
+var reconcilerMetrics = expvar.NewMap("livestream_reconciler")
+
+const (
+	// reconciler runs
+	metricRuns = "runs"
+	// livestream is in pg but not in the cache (e.g. cache write failed on start)
+	metricMissingInCache = "missing_in_cache"
+	// livestream is in the cache but not in pg (e.g. cache cleanup failed on end)
+	metricMissingInPG = "missing_in_pg"
+	// livestream is running but the channel is not published on the stream server (missed on_unpublish)
+	metricNotLive = "not_live"
+	// channel is published on the stream server but there is no livestream (missed on_publish)
+	metricNotStarted = "not_started"
+	// failed repairs of any kind
+	metricRepairErrors = "repair_errors"
+)
+
+type reconcilerStore interface {
+	CachedIDs(ctx context.Context) ([]int, error)
+	ListStored(ctx context.Context) ([]d.Livestream, error)
+	Recache(ctx context.Context, ls d.Livestream) error
+	Delete(ctx context.Context, id int) error
+}
+
+type starterEnder interface {
+	Start(ctx context.Context, username string) error
+	End(ctx context.Context, username string) error
+}
+
+// periodically diffs the livestream cache against tc_livestream and the stream server and repairs the difference.
+// update tasks of recached livestreams are resumed by Updater
+type Reconciler struct {
+	log *slog.Logger
+	rdb *redis.Client
+	ssa *streamserver.Adapter
+	lsr reconcilerStore
+	se  starterEnder
+}
+
+func NewReconciler(log *slog.Logger,
+	rdb *redis.Client,
+	ssa *streamserver.Adapter,
+	lsr reconcilerStore,
+	se starterEnder) *Reconciler {
+	return &Reconciler{log: log, rdb: rdb, ssa: ssa, lsr: lsr, se: se}
+}
+
+func (r *Reconciler) Run(ctx context.Context, timeout time.Duration) error {
+	const op = "livestream.Reconciler.Run"
+
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// one instance per interval is enough
+			ok, err := r.rdb.SetNX(ctx, "livestream_reconcile_lock", 1, timeout).Result()
+			if err != nil || !ok {
+				continue
+			}
+
+			err = r.reconcile(ctx)
+			if err != nil {
+				r.log.Error("reconcile livestreams", sl.Err(err), sl.Op(op))
+			}
+		}
+	}
+}
+
+func (r *Reconciler) reconcile(ctx context.Context) error {
+	reconcileStart := time.Now()
+	reconcilerMetrics.Add(metricRuns, 1)
+
+	cachedIDs, err := r.lsr.CachedIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	cached := make(map[int]struct{}, len(cachedIDs))
+	for _, id := range cachedIDs {
+		cached[id] = struct{}{}
+	}
+
+	stored, err := r.lsr.ListStored(ctx)
+	if err != nil {
+		return err
+	}
+
+	// without the stream server there is no way to tell which livestreams are actually running,
+	// so only the cache and pg are reconciled
+	live, err := r.liveChannels(ctx)
+	if err != nil {
+		r.log.Error("listing stream server channels", sl.Err(err))
+		live = nil
+	}
+
+	storedIDs := make(map[int]struct{}, len(stored))
+	storedChannels := make(map[string]struct{}, len(stored))
+
+	for _, ls := range stored {
+		storedIDs[ls.Id] = struct{}{}
+		storedChannels[ls.UserName] = struct{}{}
+
+		_, isCached := cached[ls.Id]
+		_, isLive := live[ls.UserName]
+		// livestreams started after the reconcile began might be missing from the stream server list
+		isNew := int64(ls.StartedAt) >= reconcileStart.Unix()
+
+		switch {
+		case !isCached && (live == nil || isLive || isNew):
+			r.drift(metricMissingInCache, ls.Id, ls.UserName)
+			r.repair(r.lsr.Recache(ctx, ls), ls.Id)
+		case !isCached:
+			// gone from both the cache and the stream server, just archive it
+			r.drift(metricMissingInCache, ls.Id, ls.UserName)
+			r.drift(metricNotLive, ls.Id, ls.UserName)
+			r.repair(r.lsr.Delete(ctx, ls.Id), ls.Id)
+		case live != nil && !isLive && !isNew:
+			r.drift(metricNotLive, ls.Id, ls.UserName)
+			r.repair(r.se.End(ctx, ls.UserName), ls.Id)
+		}
+	}
+
+	for id := range cached {
+		if _, ok := storedIDs[id]; ok {
+			continue
+		}
+
+		r.drift(metricMissingInPG, id, "")
+		err := r.lsr.Delete(ctx, id)
+		if errors.Is(err, d.ErrAlreadyEnded) {
+			err = nil
+		}
+		r.repair(err, id)
+	}
+
+	for channel := range live {
+		if _, ok := storedChannels[channel]; ok {
+			continue
+		}
+
+		r.drift(metricNotStarted, 0, channel)
+		r.repair(r.se.Start(ctx, channel), 0)
+	}
+
+	r.log.Debug("livestreams reconciled",
+		slog.Int("cached", len(cached)),
+		slog.Int("stored", len(stored)),
+		slog.Int("live", len(live)),
+		slog.Duration("took", time.Since(reconcileStart)))
+
+	return nil
+}
+
+// names of channels published on the stream server
+func (r *Reconciler) liveChannels(ctx context.Context) (map[string]struct{}, error) {
+	live := make(map[string]struct{})
+
+	moreStreams := true
+	start := 0
+	count := 500
+	for moreStreams {
+		resp, err := r.ssa.List(ctx, start, count)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.Streams) < count {
+			moreStreams = false
+		}
+
+		for _, st := range resp.Streams {
+			live[st.Name] = struct{}{}
+		}
+
+		start += count
+	}
+
+	return live, nil
+}
+
+func (r *Reconciler) drift(kind string, lsId int, channel string) {
+	reconcilerMetrics.Add(kind, 1)
+
+	r.log.Info("livestream drift",
+		slog.String("kind", kind),
+		slog.Int("livestream_id", lsId),
+		slog.String("channel", channel))
+}
+
+func (r *Reconciler) repair(err error, lsId int) {
+	if err == nil {
+		return
+	}
+
+	reconcilerMetrics.Add(metricRepairErrors, 1)
+
+	r.log.Error("repairing livestream drift",
+		sl.Err(err),
+		slog.Int("livestream_id", lsId))
+}
@@ -0,0 +1,90 @@
+package livestream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hashset to store which instance owns update task of a livestream and its scheduler entry id
+//
+// key is "livestream_update_tasks:<livestream id>", fields are "instance" and "entry".
+// asynq scheduler entries live in memory of the instance that registered them,
+// so only the owner is able to unregister the entry
+type taskStore struct {
+	rdb *redis.Client
+}
+
+// claims the task if it has no owner, is already owned by the instance
+// or its owner is dead (heartbeat key expired)
+var claimScript = redis.NewScript(`
+local owner = redis.call('HGET', KEYS[1], 'instance')
+if owner == false or owner == ARGV[1] or redis.call('EXISTS', ARGV[2] .. owner) == 0 then
+	if owner ~= ARGV[1] then
+		redis.call('HDEL', KEYS[1], 'entry')
+	end
+	redis.call('HSET', KEYS[1], 'instance', ARGV[1])
+	return 1
+end
+return 0
+`)
+
+func (r *taskStore) claim(ctx context.Context, lsId int, instanceID string) (bool, error) {
+	res, err := claimScript.Run(ctx, r.rdb,
+		[]string{r.key(lsId)},
+		instanceID,
+		r.instanceKeyPrefix()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return res == 1, nil
+}
+
+func (r *taskStore) setEntry(ctx context.Context, lsId int, entryId string) error {
+	return r.rdb.HSet(ctx, r.key(lsId), "entry", entryId).Err()
+}
+
+// returns owner instance id of every given livestream's task, "" if there is none
+func (r *taskStore) owners(ctx context.Context, lsIds []int) ([]string, error) {
+	cmds, err := r.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, id := range lsIds {
+			p.HGet(ctx, r.key(id), "instance")
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	owners := make([]string, len(lsIds))
+	for i, cmd := range cmds {
+		owner, err := cmd.(*redis.StringCmd).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		owners[i] = owner
+	}
+
+	return owners, nil
+}
+
+func (r *taskStore) release(ctx context.Context, lsId int) error {
+	return r.rdb.Del(ctx, r.key(lsId)).Err()
+}
+
+func (r *taskStore) heartbeat(ctx context.Context, instanceID string, ttl time.Duration) error {
+	return r.rdb.Set(ctx, r.instanceKeyPrefix()+instanceID, 1, ttl).Err()
+}
+
+func (r *taskStore) key(lsId int) string {
+	return fmt.Sprintf("livestream_update_tasks:%d", lsId)
+}
+
+func (r *taskStore) instanceKeyPrefix() string {
+	return "livestream_updaters:"
+}
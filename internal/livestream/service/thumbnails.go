@@ -0,0 +1,66 @@
+package livestream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+	d "twitchy-api/internal/livestream/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// thumbnails of livestreams whose update task stopped refreshing them expire after this
+const thumbnailTTL = 10 * time.Minute
+
+// keeps the latest thumbnail of every livestream in redis, so every instance can serve it.
+// key is "livestream_thumbnail:<livestream id>", fields are "name" and "image".
+// urls are "<urlPrefix>/<livestream id>/<sha256 of image>.png" and change only when the image does
+type thumbnailStore struct {
+	rdb       *redis.Client
+	urlPrefix string
+}
+
+// replaces the thumbnail of the livestream. returns url path of the thumbnail
+func (t *thumbnailStore) save(ctx context.Context, lsId int, img []byte) (string, error) {
+	sum := sha256.Sum256(img)
+	name := hex.EncodeToString(sum[:]) + ".png"
+
+	_, err := t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, t.key(lsId), "name", name, "image", img)
+		pipe.Expire(ctx, t.key(lsId), thumbnailTTL)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return t.urlPrefix + "/" + strconv.Itoa(lsId) + "/" + name, nil
+}
+
+// image of the thumbnail, d.ErrThumbnailNotFound if the livestream has another one by now
+func (t *thumbnailStore) get(ctx context.Context, lsId int, name string) ([]byte, error) {
+	res, err := t.rdb.HMGet(ctx, t.key(lsId), "name", "image").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cur, _ := res[0].(string)
+	img, _ := res[1].(string)
+	if cur == "" || cur != name {
+		return nil, d.ErrThumbnailNotFound
+	}
+
+	return []byte(img), nil
+}
+
+// removes the thumbnail of the livestream
+func (t *thumbnailStore) remove(ctx context.Context, lsId int) error {
+	return t.rdb.Del(ctx, t.key(lsId)).Err()
+}
+
+func (t *thumbnailStore) key(lsId int) string {
+	return fmt.Sprintf("livestream_thumbnail:%d", lsId)
+}
@@ -5,9 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
-	"math/rand"
-	"os"
 	"strconv"
+	"sync"
 	"time"
 	"twitchy-api/internal/external/streamserver"
 	"twitchy-api/internal/lib/sl"
@@ -18,21 +17,35 @@ import (
 
 type Store interface {
 	Create(ctx context.Context, cr d.LivestreamCreate) (*d.Livestream, error)
+	GetByUsername(ctx context.Context, username string) (*d.Livestream, error)
+	Delete(ctx context.Context, id int) error
 	Update(ctx context.Context, id int, upd d.LivestreamUpdate) (*d.Livestream, error)
 	List(ctx context.Context, s d.LivestreamSearch) ([]d.Livestream, error)
+	ListAll(ctx context.Context) ([]d.Livestream, error)
 	UpdateViewers(ctx context.Context, id int, viewers int) error
 	UpdateThumbnail(ctx context.Context, id int, thumbnail string) error
 }
 
+type scheduler interface {
+	Schedule(every time.Duration, taskType string, payload []byte, taskId string) (string, error)
+	Unregister(entryId string) error
+}
+
 // TODO: add polling count, polling timeout to config
 type Updater struct {
 	log        *slog.Logger
 	ssa        *streamserver.Adapter
 	lsr        Store
 	sched      scheduler
-	previews   []os.DirEntry
+	thumbs     *thumbnailStore
 	instanceID string
-	rdb        *redis.Client
+
+	// which instance owns update task of a livestream (shared between instances)
+	tasks *taskStore
+
+	mu sync.Mutex
+	// scheduler entry ids of update tasks registered by this instance by livestream id
+	entries map[int]string
 }
 
 func NewUpdater(log *slog.Logger,
@@ -41,12 +54,6 @@ func NewUpdater(log *slog.Logger,
 	lsr Store,
 	sched scheduler,
 	instanceID string) *Updater {
-	entries, err := os.ReadDir("./static/livestreamthumbs")
-	if err != nil {
-		log.Error("Unable to read ./static/livestreamthumbs. Cancelling updates for livestreams.", sl.Err(err))
-		return nil
-	}
-
 	log = log.With(slog.String("instance_id", instanceID))
 
 	return &Updater{
@@ -54,105 +61,132 @@ func NewUpdater(log *slog.Logger,
 		ssa:        ssa,
 		lsr:        lsr,
 		sched:      sched,
-		previews:   entries,
+		thumbs:     &thumbnailStore{rdb: rdb, urlPrefix: "livestreamthumbs"},
 		instanceID: instanceID,
-		rdb:        rdb}
+		tasks:      &taskStore{rdb: rdb},
+		entries:    make(map[int]string)}
 }
 
+// livestreams are started and ended by srs webhooks (see Start and End),
+// missed events are repaired by Reconciler
+//
 // TODO: ttl
 func (s *Updater) Run(ctx context.Context, timeout time.Duration) error {
-	err := s.startup(ctx)
+	// instance is considered dead (and its tasks are taken over) after missing a few heartbeats
+	heartbeatTTL := 3 * timeout
+
+	err := s.tasks.heartbeat(ctx, s.instanceID, heartbeatTTL)
 	if err != nil {
-		s.log.Info("startup", sl.Err(err))
+		s.log.Error("heartbeat", sl.Err(err))
 		return err
 	}
 
-	err = s.pollSRS(ctx)
+	err = s.Resume(ctx)
 	if err != nil {
-		s.log.Error("poll srs", sl.Err(err))
+		s.log.Error("resume tasks", sl.Err(err))
 		return err
 	}
 
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-time.Tick(timeout):
-			err := s.pollSRS(ctx)
+		case <-ticker.C:
+			err := s.tasks.heartbeat(ctx, s.instanceID, heartbeatTTL)
 			if err != nil {
-				s.log.Error("poll srs", sl.Err(err))
-				return err
+				s.log.Error("heartbeat", sl.Err(err))
 			}
-		}
-	}
-}
 
-const (
-	TaskUpdate = "livestream:update"
-)
+			err = s.sweepTasks(ctx)
+			if err != nil {
+				s.log.Error("sweep tasks", sl.Err(err))
+			}
 
-type updateTaskPayload struct {
-	LivestreamID int
-	Username     string
+			// takes over tasks of instances that are gone
+			err = s.Resume(ctx)
+			if err != nil {
+				s.log.Error("resume tasks", sl.Err(err))
+			}
+		}
+	}
 }
 
-// polls srs and registers new update tasks
-func (s *Updater) pollSRS(ctx context.Context) error {
-	lockValue := s.instanceID + ":" + strconv.FormatInt(time.Now().Unix(), 10)
+// starts livestream for the channel and registers update task for it.
+// starting already started livestream is not an error
+func (s *Updater) Start(ctx context.Context, username string) error {
+	ls, err := s.lsr.Create(ctx, d.LivestreamCreate{Username: username})
+	if err != nil {
+		if errors.Is(err, d.ErrAlreadyStarted) {
+			s.log.Debug("livestream already started", slog.String("channel", username))
+			return nil
+		}
 
-	ok, err := s.rdb.SetNX(ctx, "srs_lock", lockValue, 30*time.Second).Result()
-	if err != nil || !ok {
-		return nil
+		return err
 	}
 
-	defer func() {
-		s.rdb.GetDel(ctx, "srs_lock")
-	}()
+	s.log.Info("livestream started",
+		slog.Int("livestream_id", ls.Id),
+		slog.String("channel", username))
 
-	moreStreams := true
-	start := 0
-	count := 500
-	for moreStreams {
-		s.log.Debug("polling srs",
-			slog.Int("start", start),
-			slog.Int("count", count))
+	return s.newTask(ctx, ls)
+}
 
-		resp, err := s.ssa.List(ctx, start, count)
-		if err != nil {
-			return err
+// ends livestream of the channel.
+// ending livestream that is not running is not an error
+func (s *Updater) End(ctx context.Context, username string) error {
+	ls, err := s.lsr.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, d.ErrNotFound) {
+			s.log.Debug("livestream already ended", slog.String("channel", username))
+			return nil
 		}
 
-		if len(resp.Streams) < count {
-			moreStreams = false
-		}
+		return err
+	}
 
-		for _, st := range resp.Streams {
-			ls, err := s.lsr.Create(ctx, d.LivestreamCreate{Username: st.Name})
-			if err != nil {
-				if errors.Is(err, d.ErrAlreadyStarted) {
-					// s.log.Debug("livestream already started",
-					// 	slog.String("username", st.Name),
-					// 	slog.String("livestream_id", st.ID))
-					continue
-				}
-				s.log.Error("creating livestream",
-					sl.Err(err),
-					slog.String("username", st.Name),
-					slog.String("livestream_id", st.ID))
-				continue
-			}
+	err = s.lsr.Delete(ctx, ls.Id)
+	if err != nil && !errors.Is(err, d.ErrAlreadyEnded) {
+		return err
+	}
 
-			err = s.newTask(ls)
-			if err != nil {
-				s.log.Error("registering new task", sl.Err(err))
-			}
-			start += count
-		}
+	err = s.removeTask(ctx, ls.Id)
+	if err != nil {
+		s.log.Error("unregistering update task",
+			sl.Err(err),
+			slog.Int("livestream_id", ls.Id))
 	}
 
+	err = s.thumbs.remove(ctx, ls.Id)
+	if err != nil {
+		s.log.Error("removing thumbnails",
+			sl.Err(err),
+			slog.Int("livestream_id", ls.Id))
+	}
+
+	s.log.Info("livestream ended",
+		slog.Int("livestream_id", ls.Id),
+		slog.String("channel", username))
+
 	return nil
 }
 
+// image of the thumbnail with the url name, d.ErrThumbnailNotFound once it is replaced or expired
+func (s *Updater) Thumbnail(ctx context.Context, lsId int, name string) ([]byte, error) {
+	return s.thumbs.get(ctx, lsId, name)
+}
+
+const (
+	TaskUpdate = "livestream:update"
+)
+
+type updateTaskPayload struct {
+	LivestreamID int
+	Username     string
+}
+
 func (s *Updater) HandleUpdateTask(ctx context.Context, payload []byte) error {
 	var p updateTaskPayload
 	if err := json.Unmarshal(payload, &p); err != nil {
@@ -160,11 +194,29 @@ func (s *Updater) HandleUpdateTask(ctx context.Context, payload []byte) error {
 	}
 	resp, err := s.ssa.Get(ctx, p.Username)
 	if err != nil {
+		if errors.Is(err, streamserver.ErrStreamNotFound) {
+			s.log.Debug("livestream is gone from stream server, ending",
+				slog.Int("livestream_id", p.LivestreamID),
+				slog.String("username", p.Username))
+
+			err = s.End(ctx, p.Username)
+			if err != nil {
+				return err
+			}
+
+			return s.removeTask(ctx, p.LivestreamID)
+		}
+
 		return err
 	}
 
 	err = s.lsr.UpdateViewers(ctx, p.LivestreamID, resp.Stream.Clients)
 	if err != nil {
+		// livestream has ended since the task was scheduled
+		if errors.Is(err, d.ErrNotFound) {
+			return s.removeTask(ctx, p.LivestreamID)
+		}
+
 		s.log.Error("updating viewers",
 			sl.Err(err),
 			slog.Int("livestream_id", p.LivestreamID))
@@ -176,49 +228,68 @@ func (s *Updater) HandleUpdateTask(ctx context.Context, payload []byte) error {
 			slog.Int("viewers", resp.Stream.Clients))
 	}
 
-	thumbnailId := rand.Intn(len(s.previews))
-	thumbnail := s.previews[thumbnailId].Name()
-	err = s.lsr.UpdateThumbnail(ctx, p.LivestreamID, "livestreamthumbs/"+thumbnail)
+	img, err := s.ssa.Snapshot(ctx, p.Username)
+	if err != nil {
+		// snapshot appears some time after the stream starts, keep the previous thumbnail until then
+		if errors.Is(err, streamserver.ErrSnapshotNotFound) {
+			s.log.Debug("no snapshot yet", slog.Int("livestream_id", p.LivestreamID))
+			return nil
+		}
+
+		s.log.Error("getting snapshot",
+			sl.Err(err),
+			slog.Int("livestream_id", p.LivestreamID))
+		return nil
+	}
+
+	thumbnail, err := s.thumbs.save(ctx, p.LivestreamID, img)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return s.lsr.UpdateThumbnail(ctx, p.LivestreamID, thumbnail)
 }
 
-func (s *Updater) startup(ctx context.Context) error {
-	moreStreams := true
-	page := 1
-	count := 500
-	for moreStreams {
-		livestreams, err := s.lsr.List(ctx, d.LivestreamSearch{
-			Category: "dota-2",
-			Page:     page,
-			Count:    count,
-		})
+// registers update tasks for all running livestreams that have none, regardless of category.
+// livestreams which tasks are already registered by this instance
+// or owned by another live instance are skipped
+func (s *Updater) Resume(ctx context.Context) error {
+	livestreams, err := s.lsr.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, ls := range livestreams {
+		err := s.newTask(ctx, &ls)
 		if err != nil {
-			return err
+			s.log.Error("resuming update task",
+				sl.Err(err),
+				slog.Int("livestream_id", ls.Id))
 		}
+	}
 
-		for _, ls := range livestreams {
-			s.newTask(&ls)
-		}
+	return nil
+}
 
-		if len(livestreams) < count {
-			moreStreams = false
-		}
+func (s *Updater) newTask(ctx context.Context, ls *d.Livestream) error {
+	s.mu.Lock()
+	_, ok := s.entries[ls.Id]
+	s.mu.Unlock()
 
-		page += 1
+	if ok {
+		return nil
 	}
 
-	return nil
-}
+	claimed, err := s.tasks.claim(ctx, ls.Id, s.instanceID)
+	if err != nil {
+		return err
+	}
 
-type scheduler interface {
-	Schedule(every time.Duration, taskType string, payload []byte, taskId string) (string, error)
-}
+	if !claimed {
+		s.log.Debug("update task is owned by another instance", slog.Int("livestream_id", ls.Id))
+		return nil
+	}
 
-func (s *Updater) newTask(ls *d.Livestream) error {
 	s.log.Debug("scheduling update task",
 		slog.Int("livestream_id", ls.Id),
 		slog.String("channel", ls.UserName))
@@ -228,12 +299,80 @@ func (s *Updater) newTask(ls *d.Livestream) error {
 		return err
 	}
 
-	// TODO: save {entry: entryId, livestream: ls.Id} to delete task later
 	// register new task with taskId = asynq.TaskID to make sure there is no duplicate update tasks for a given livestream
-	_, err = s.sched.Schedule(time.Second*15, TaskUpdate, payload, strconv.Itoa(ls.Id))
+	entryId, err := s.sched.Schedule(time.Second*15, TaskUpdate, payload, strconv.Itoa(ls.Id))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.entries[ls.Id] = entryId
+	s.mu.Unlock()
+
+	return s.tasks.setEntry(ctx, ls.Id, entryId)
+}
+
+// unregisters update task if it is owned by this instance and releases it.
+// tasks owned by other instances are unregistered by their owners on the next sweep
+func (s *Updater) removeTask(ctx context.Context, lsId int) error {
+	s.mu.Lock()
+	entryId, ok := s.entries[lsId]
+	delete(s.entries, lsId)
+	s.mu.Unlock()
+
+	if ok {
+		s.log.Debug("unregistering update task", slog.Int("livestream_id", lsId))
+
+		err := s.sched.Unregister(entryId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.tasks.release(ctx, lsId)
+}
+
+// unregisters update tasks of this instance that were released or taken over by other instances
+func (s *Updater) sweepTasks(ctx context.Context) error {
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	owners, err := s.tasks.owners(ctx, ids)
 	if err != nil {
 		return err
 	}
 
+	for i, id := range ids {
+		if owners[i] == s.instanceID {
+			continue
+		}
+
+		s.mu.Lock()
+		entryId, ok := s.entries[id]
+		delete(s.entries, id)
+		s.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		s.log.Debug("unregistering released update task", slog.Int("livestream_id", id))
+
+		err := s.sched.Unregister(entryId)
+		if err != nil {
+			s.log.Error("unregistering update task",
+				sl.Err(err),
+				slog.Int("livestream_id", id))
+		}
+	}
+
 	return nil
 }
@@ -99,6 +99,57 @@ func (r *cache) list(ctx context.Context, category string, page, count int) ([]d
 	return res, nil
 }
 
+// unlike list, doesn't page over sorted sets, so livestreams can't be skipped
+// or repeated when their viewers change in between
+func (r *cache) listAll(ctx context.Context) ([]d.Livestream, error) {
+	ids, err := r.ids.getAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := r.store.list(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// ids of partially deleted livestreams might still be in the set
+	res := make([]d.Livestream, 0, len(all))
+	for _, ls := range all {
+		if ls.Id == 0 {
+			continue
+		}
+
+		res = append(res, ls)
+	}
+
+	return res, nil
+}
+
+// livestreams of the users who are live, keyed by username
+func (r *cache) listByUsernames(ctx context.Context, usernames []string) (map[string]d.Livestream, error) {
+	ids, err := r.userMap.getMany(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := r.store.list(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]d.Livestream, len(all))
+	for _, ls := range all {
+		// the livestream might be deleted after its id was read
+		if ls.Id == 0 {
+			continue
+		}
+
+		res[ls.UserName] = ls
+	}
+
+	return res, nil
+}
+
 func (r *cache) update(ctx context.Context, lsId int, title string, u d.User, c d.Category) (*d.Livestream, error) {
 	cmds, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
 		r.userMap.addTx(ctx, p, u.Name, lsId)
@@ -141,7 +192,7 @@ func (r *cache) updateViewers(ctx context.Context, id int, viewers int) error {
 	cmds, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
 		r.sorted.addTx(ctx, p, ls.CategoryLink, viewers, id)
 		r.sortedAll.addTx(ctx, p, viewers, id)
-		r.store.updateViewers(ctx, id, int(viewers))
+		r.store.updateViewersTx(ctx, p, ls, viewers)
 		return nil
 	})
 
@@ -161,6 +212,19 @@ func (r *cache) updateViewers(ctx context.Context, id int, viewers int) error {
 func (r *cache) delete(ctx context.Context, id int) error {
 	ls, err := r.store.get(ctx, id)
 	if err != nil {
+		if errors.Is(err, d.ErrNotFound) {
+			// livestream itself is gone, but its id might be left in the indexes
+			// which don't need the livestream to be found
+			_, perr := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+				r.sortedAll.deleteTx(ctx, p, id)
+				r.ids.deleteTx(ctx, p, id)
+				return nil
+			})
+			if perr != nil {
+				return fmt.Errorf("pipeline failed: %w", perr)
+			}
+		}
+
 		return err
 	}
 
@@ -170,8 +234,8 @@ func (r *cache) delete(ctx context.Context, id int) error {
 
 	cmds, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
 		r.userMap.deleteTx(ctx, p, ls.UserName)
-		r.sorted.deleteTx(ctx, p, ls.CategoryLink)
-		r.sortedAll.deleteTx(ctx, p)
+		r.sorted.deleteTx(ctx, p, ls.CategoryLink, id)
+		r.sortedAll.deleteTx(ctx, p, id)
 		r.store.deleteTx(ctx, p, id)
 		r.ids.deleteTx(ctx, p, id)
 		return nil
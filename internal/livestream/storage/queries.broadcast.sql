@@ -0,0 +1,27 @@
+-- name: BroadcastSelectMany :many
+SELECT
+    h.id,
+    h.title,
+    h.started_at,
+    h.ended_at,
+    h.peak_viewers,
+    h.avg_viewers,
+    c.id AS category_id,
+    c.link AS category_link,
+    c.name AS category_name
+FROM
+    tc_livestream_history h
+JOIN
+    tc_user u
+ON
+    h.id_user = u.id
+JOIN
+    tc_category c
+ON
+    h.id_category = c.id
+WHERE
+    u.name = $1
+ORDER BY
+    h.started_at DESC
+LIMIT $2
+OFFSET $3;
@@ -69,16 +69,83 @@ ON
     inserted.id_category = c.id;
 
 
--- name: LivestreamDelete :exec
+-- name: LivestreamSelectAll :many
+SELECT
+    ls.id AS livestream_id,
+    u.id AS user_id,
+    u.pfp AS user_pfp,
+    u.name AS user_name,
+    ls.title AS title,
+    c.id AS category_id,
+    c.link AS category_link,
+    c.name AS category_name,
+    ls.started_at,
+    ls.viewers
+FROM
+    tc_livestream ls
+JOIN
+    tc_user u
+ON
+    ls.id_user = u.id
+JOIN
+    tc_category c
+ON
+    ls.id_category = c.id;
+
+
+-- name: LivestreamDelete :one
 DELETE FROM
     tc_livestream l
 WHERE
-    l.id = $1;
+    l.id = $1
+RETURNING *;
+
+
+-- name: LivestreamUpdateViewersMany :exec
+UPDATE tc_livestream AS ls
+    SET
+        viewers = v.viewers
+    FROM
+        (SELECT unnest(@ids::int[]) AS id, unnest(@viewers::int[]) AS viewers) AS v
+    WHERE
+        ls.id = v.id AND ls.viewers <> v.viewers;
+
+
+-- name: LivestreamHistoryInsert :one
+INSERT INTO tc_livestream_history (
+    id,
+    id_user,
+    id_category,
+    title,
+    started_at,
+    peak_viewers,
+    avg_viewers
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+
+-- name: LivestreamUserSetLive :exec
+UPDATE tc_user
+    SET
+        is_live = TRUE,
+        first_livestream = COALESCE(first_livestream, CURRENT_DATE)
+    WHERE
+        id = $1;
 
 
--- name: LivestreamUpdateViewers :exec
-UPDATE tc_livestream
+-- name: LivestreamUserSetOffline :exec
+UPDATE tc_user
     SET
-        viewers = $2
+        is_live = FALSE,
+        last_livestream = CURRENT_DATE
     WHERE
         id = $1;
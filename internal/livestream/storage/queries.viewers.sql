@@ -0,0 +1,26 @@
+-- name: ViewersInsertMany :exec
+INSERT INTO tc_livestream_viewers (
+    id_livestream,
+    sampled_at,
+    viewers
+)
+SELECT
+    unnest(@ids::int[]),
+    unnest(@sampled_at::timestamptz[]),
+    unnest(@viewers::int[])
+ON CONFLICT DO NOTHING;
+
+
+-- name: ViewersSelect :many
+SELECT
+    date_bin(@resolution::interval, sampled_at, '2000-01-01'::timestamptz)::timestamptz AS bucket,
+    MAX(viewers)::int AS peak_viewers,
+    AVG(viewers)::int AS avg_viewers
+FROM
+    tc_livestream_viewers
+WHERE
+    id_livestream = @id_livestream
+GROUP BY
+    bucket
+ORDER BY
+    bucket;
@@ -2,15 +2,58 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"twitchy-api/internal/external/db"
+	d "twitchy-api/internal/livestream/domain"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type queriesAdapter struct {
 	queries *db.Queries
 }
 
-func (q *queriesAdapter) Delete(ctx context.Context, id int) error {
-	return q.queries.LivestreamDelete(ctx, int32(id))
+// "returning" in the underlying query is needed to make sure pgx.ErrNoRows is returned
+func (q *queriesAdapter) Delete(ctx context.Context, id int) (db.TcLivestream, error) {
+	deleted, err := q.queries.LivestreamDelete(ctx, int32(id))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.TcLivestream{}, d.ErrAlreadyEnded
+		}
+
+		return db.TcLivestream{}, err
+	}
+
+	return deleted, nil
+}
+
+func (q *queriesAdapter) InsertHistory(ctx context.Context, arg db.LivestreamHistoryInsertParams) (db.TcLivestreamHistory, error) {
+	return q.queries.LivestreamHistoryInsert(ctx, arg)
+}
+
+func (q *queriesAdapter) ListBroadcasts(ctx context.Context, arg db.BroadcastSelectManyParams) ([]db.BroadcastSelectManyRow, error) {
+	return q.queries.BroadcastSelectMany(ctx, arg)
+}
+
+func (q *queriesAdapter) InsertViewers(ctx context.Context, arg db.ViewersInsertManyParams) error {
+	return q.queries.ViewersInsertMany(ctx, arg)
+}
+
+func (q *queriesAdapter) ListViewers(ctx context.Context, arg db.ViewersSelectParams) ([]db.ViewersSelectRow, error) {
+	return q.queries.ViewersSelect(ctx, arg)
+}
+
+func (q *queriesAdapter) SetUserLive(ctx context.Context, userId int) error {
+	return q.queries.LivestreamUserSetLive(ctx, int32(userId))
+}
+
+func (q *queriesAdapter) SetUserOffline(ctx context.Context, userId int) error {
+	return q.queries.LivestreamUserSetOffline(ctx, int32(userId))
+}
+
+func (q *queriesAdapter) SelectAll(ctx context.Context) ([]db.LivestreamSelectAllRow, error) {
+	return q.queries.LivestreamSelectAll(ctx)
 }
 
 func (q *queriesAdapter) Insert(ctx context.Context, username string) (db.LivestreamInsertRow, error) {
@@ -21,6 +64,6 @@ func (q *queriesAdapter) Update(ctx context.Context, arg db.LivestreamUpdatePara
 	return q.queries.LivestreamUpdate(ctx, arg)
 }
 
-func (q *queriesAdapter) UpdateViewers(ctx context.Context, arg db.LivestreamUpdateViewersParams) error {
-	return q.queries.LivestreamUpdateViewers(ctx, arg)
+func (q *queriesAdapter) UpdateViewersMany(ctx context.Context, arg db.LivestreamUpdateViewersManyParams) error {
+	return q.queries.LivestreamUpdateViewersMany(ctx, arg)
 }
@@ -2,7 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 	"twitchy-api/internal/external/db"
 	d "twitchy-api/internal/livestream/domain"
 
@@ -13,17 +16,19 @@ import (
 
 // TODO: sync redis and pg
 // cleanup if updater can't get livestream data
-// TODO: add to tc_livestream_history upon livestream end
 type RepositoryImpl struct {
 	pool *pgxpool.Pool
 	// cache is actually primary database for livestreams
 	cache *cache
+	// viewer samples not yet written to pg, see FlushViewers
+	samples *sampleBuffer
 }
 
 func NewRepo(rdb *redis.Client, pool *pgxpool.Pool) *RepositoryImpl {
 	return &RepositoryImpl{
-		cache: newCache(rdb),
-		pool:  pool,
+		cache:   newCache(rdb),
+		pool:    pool,
+		samples: &sampleBuffer{rdb: rdb},
 	}
 }
 
@@ -34,6 +39,10 @@ func (r *RepositoryImpl) Get(ctx context.Context, id int) (*d.Livestream, error)
 func (r *RepositoryImpl) GetByUsername(ctx context.Context, username string) (*d.Livestream, error) {
 	id, err := r.cache.userMap.get(ctx, username)
 	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("%s's livestream: %w", username, d.ErrNotFound)
+		}
+
 		return nil, fmt.Errorf("%s's livestream not found: %v", username, err)
 	}
 
@@ -44,6 +53,16 @@ func (r *RepositoryImpl) List(ctx context.Context, s d.LivestreamSearch) ([]d.Li
 	return r.cache.list(ctx, s.Category, s.Page, s.Count)
 }
 
+// livestreams of the channels that are live, keyed by channel name. channels which are offline are not in the map
+func (r *RepositoryImpl) ListByUsernames(ctx context.Context, usernames []string) (map[string]d.Livestream, error) {
+	return r.cache.listByUsernames(ctx, usernames)
+}
+
+// all livestreams in the cache in no particular order
+func (r *RepositoryImpl) ListAll(ctx context.Context) ([]d.Livestream, error) {
+	return r.cache.listAll(ctx)
+}
+
 func (r *RepositoryImpl) Create(ctx context.Context, cr d.LivestreamCreate) (*d.Livestream, error) {
 	exists, err := r.cache.existsByUsername(ctx, cr.Username)
 
@@ -57,7 +76,25 @@ func (r *RepositoryImpl) Create(ctx context.Context, cr d.LivestreamCreate) (*d.
 
 	q := queriesAdapter{queries: db.New(r.pool)}
 
-	ins, err := q.Insert(ctx, cr.Username)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) // nolint:errcheck
+
+	qtx := queriesAdapter{queries: q.queries.WithTx(tx)}
+
+	ins, err := qtx.Insert(ctx, cr.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = qtx.SetUserLive(ctx, int(ins.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +102,7 @@ func (r *RepositoryImpl) Create(ctx context.Context, cr d.LivestreamCreate) (*d.
 	ls := d.Livestream{
 		Id:           int(ins.LivestreamID),
 		Title:        ins.Title.String,
-		StartedAt:    ins.StartedAt.Time.Second(),
+		StartedAt:    int(ins.StartedAt.Time.Unix()),
 		UserId:       int(ins.UserID),
 		UserName:     ins.UserName,
 		UserPfp:      ins.UserPfp.String,
@@ -83,21 +120,169 @@ func (r *RepositoryImpl) Create(ctx context.Context, cr d.LivestreamCreate) (*d.
 	return &ls, nil
 }
 
+// loads livestreams from tc_livestream into the cache.
+// livestreams that are already cached are left as is since the cache is fresher.
+// returns count of added livestreams
+func (r *RepositoryImpl) WarmUp(ctx context.Context) (int, error) {
+	stored, err := r.ListStored(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, ls := range stored {
+		exists, err := r.cache.store.exists(ctx, ls.Id)
+		if err != nil {
+			return added, err
+		}
+
+		if exists > 0 {
+			continue
+		}
+
+		err = r.Recache(ctx, ls)
+		if err != nil {
+			return added, err
+		}
+
+		added += 1
+	}
+
+	return added, nil
+}
+
+// all running livestreams from tc_livestream, bypassing the cache
+func (r *RepositoryImpl) ListStored(ctx context.Context) ([]d.Livestream, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+	rows, err := q.SelectAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	livestreams := make([]d.Livestream, len(rows))
+	for i, row := range rows {
+		livestreams[i] = d.Livestream{
+			Id:           int(row.LivestreamID),
+			Title:        row.Title.String,
+			Viewers:      int(row.Viewers),
+			StartedAt:    int(row.StartedAt.Time.Unix()),
+			UserId:       int(row.UserID),
+			UserName:     row.UserName,
+			UserPfp:      row.UserPfp.String,
+			CategoryId:   int(row.CategoryID),
+			CategoryName: row.CategoryName,
+			CategoryLink: row.CategoryLink,
+		}
+	}
+
+	return livestreams, nil
+}
+
+// ids of all livestreams in the cache
+func (r *RepositoryImpl) CachedIDs(ctx context.Context) ([]int, error) {
+	return r.cache.ids.getAll(ctx)
+}
+
+// puts livestream (usually taken from ListStored) back into the cache
+func (r *RepositoryImpl) Recache(ctx context.Context, ls d.Livestream) error {
+	return r.cache.add(ctx, ls)
+}
+
+// updates viewers in the cache only, pg is synced in batches by SyncViewers
 func (r *RepositoryImpl) UpdateViewers(ctx context.Context, id int, viewers int) error {
-	// TODO: batch updates
-	// go func() {
-	// 	q := queriesAdapter{queries: db.New(r.pool)}
+	err := r.cache.updateViewers(ctx, id, viewers)
+	if err != nil {
+		return err
+	}
+
+	return r.samples.add(ctx, id, viewers, time.Now())
+}
+
+// writes buffered viewer samples to pg, a batch per query, until the buffer is drained.
+// a batch is dropped if its write fails
+func (r *RepositoryImpl) FlushViewers(ctx context.Context) (int, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+
+	total := 0
+	for {
+		samples, err := r.samples.take(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		if len(samples.Ids) == 0 {
+			return total, nil
+		}
+
+		err = q.InsertViewers(ctx, samples)
+		if err != nil {
+			return total, fmt.Errorf("unable to write %d viewer samples: %w", len(samples.Ids), err)
+		}
+
+		total += len(samples.Ids)
+
+		if len(samples.Ids) < samplesBatch {
+			return total, nil
+		}
+	}
+}
 
-	// 	err := q.UpdateViewers(ctx, db.LivestreamUpdateViewersParams{
-	// 		Viewers: viewers,
-	// 		Name:    user,
-	// 	})
-	// 	if err != nil {
-	// 		slog.Error("unable to update viewers", sl.Err(err))
-	// 	}
-	// }()
+// copies current viewers of all livestreams from the cache to tc_livestream in one query
+func (r *RepositoryImpl) SyncViewers(ctx context.Context) (int, error) {
+	all, err := r.cache.sortedAll.getAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(all) == 0 {
+		return 0, nil
+	}
+
+	arg := db.LivestreamUpdateViewersManyParams{
+		Ids:     make([]int32, 0, len(all)),
+		Viewers: make([]int32, 0, len(all)),
+	}
+
+	for _, z := range all {
+		id, err := strconv.Atoi(fmt.Sprint(z.Member))
+		if err != nil {
+			continue
+		}
+
+		arg.Ids = append(arg.Ids, int32(id))
+		arg.Viewers = append(arg.Viewers, int32(z.Score))
+	}
+
+	q := queriesAdapter{queries: db.New(r.pool)}
+	err = q.UpdateViewersMany(ctx, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(arg.Ids), nil
+}
+
+// viewer samples of a livestream (running or ended) aggregated into buckets of given resolution
+func (r *RepositoryImpl) ListViewers(ctx context.Context, id int, resolution time.Duration) ([]d.ViewersSample, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+	rows, err := q.ListViewers(ctx, db.ViewersSelectParams{
+		Resolution:   pgtype.Interval{Microseconds: resolution.Microseconds(), Valid: true},
+		IDLivestream: int32(id),
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return r.cache.updateViewers(ctx, id, viewers)
+	samples := make([]d.ViewersSample, len(rows))
+	for i, row := range rows {
+		samples[i] = d.ViewersSample{
+			Time:        row.Bucket.Time,
+			PeakViewers: int(row.PeakViewers),
+			AvgViewers:  int(row.AvgViewers),
+		}
+	}
+
+	return samples, nil
 }
 
 func (r *RepositoryImpl) Update(ctx context.Context, id int, upd d.LivestreamUpdate) (*d.Livestream, error) {
@@ -132,12 +317,102 @@ func (r *RepositoryImpl) UpdateThumbnail(ctx context.Context, id int, thumbnail
 	return r.cache.updateThumbnail(ctx, id, thumbnail)
 }
 
-func (r *RepositoryImpl) Delete(ctx context.Context, id int) error {
-	err := r.cache.delete(ctx, id)
+func (r *RepositoryImpl) ListBroadcasts(ctx context.Context, s d.BroadcastSearch) ([]d.Broadcast, error) {
+	q := queriesAdapter{queries: db.New(r.pool)}
+	rows, err := q.ListBroadcasts(ctx, db.BroadcastSelectManyParams{
+		Name:   s.Channel,
+		Limit:  int32(s.Count),
+		Offset: int32((s.Page - 1) * s.Count),
+	})
 	if err != nil {
+		return nil, err
+	}
+
+	broadcasts := make([]d.Broadcast, len(rows))
+	for i, row := range rows {
+		broadcasts[i] = d.Broadcast{
+			Id:           int(row.ID),
+			Title:        row.Title.String,
+			StartedAt:    row.StartedAt.Time,
+			EndedAt:      row.EndedAt.Time,
+			PeakViewers:  int(row.PeakViewers),
+			AvgViewers:   int(row.AvgViewers),
+			CategoryId:   int(row.CategoryID),
+			CategoryName: row.CategoryName,
+			CategoryLink: row.CategoryLink,
+		}
+	}
+
+	return broadcasts, nil
+}
+
+// ends livestream: moves it from tc_livestream to tc_livestream_history (keeping its id),
+// marks its owner as offline and removes it from the cache
+func (r *RepositoryImpl) Delete(ctx context.Context, id int) error {
+	// viewer stats live only in the cache, so read them before anything is removed
+	var peak, avg int
+	cached, err := r.cache.get(ctx, id)
+	if err != nil && !errors.Is(err, d.ErrNotFound) {
 		return err
 	}
+	if cached != nil {
+		peak, avg = cached.PeakViewers, cached.AvgViewers()
+	}
 
 	q := queriesAdapter{queries: db.New(r.pool)}
-	return q.Delete(ctx, id)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // nolint:errcheck
+
+	qtx := queriesAdapter{queries: q.queries.WithTx(tx)}
+
+	ended, err := qtx.Delete(ctx, id)
+	pgEnded := errors.Is(err, d.ErrAlreadyEnded)
+	if err != nil && !pgEnded {
+		return err
+	}
+
+	if !pgEnded {
+		_, err = qtx.InsertHistory(ctx, db.LivestreamHistoryInsertParams{
+			ID:          ended.ID,
+			IDUser:      ended.IDUser,
+			IDCategory:  ended.IDCategory,
+			Title:       ended.Title,
+			StartedAt:   ended.StartedAt,
+			PeakViewers: int32(peak),
+			AvgViewers:  int32(avg),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to archive livestream: %w", err)
+		}
+
+		err = qtx.SetUserOffline(ctx, int(ended.IDUser))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// livestream might be already gone from pg but not from the cache, so clean up regardless
+	err = r.cache.delete(ctx, id)
+	if err != nil {
+		if errors.Is(err, d.ErrNotFound) {
+			if pgEnded {
+				return d.ErrAlreadyEnded
+			}
+
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"twitchy-api/internal/external/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+)
+
+const samplesKey = "livestream_viewer_samples"
+
+// samples written to pg in one query
+const samplesBatch = 5000
+
+// buffer of viewer samples waiting to be written to tc_livestream_viewers in batches.
+// it is a redis list shared by every instance, so samples of an instance that stops
+// before flushing are written by the others.
+// entries are "<livestream id>:<viewers>:<unix time>"
+type sampleBuffer struct {
+	rdb *redis.Client
+}
+
+func (b *sampleBuffer) add(ctx context.Context, id int, viewers int, at time.Time) error {
+	return b.rdb.RPush(ctx, samplesKey, fmt.Sprintf("%d:%d:%d", id, viewers, at.Unix())).Err()
+}
+
+// removes up to samplesBatch oldest samples from the buffer and returns them.
+// malformed entries are skipped
+func (b *sampleBuffer) take(ctx context.Context) (db.ViewersInsertManyParams, error) {
+	var samples db.ViewersInsertManyParams
+
+	entries, err := b.rdb.LPopCount(ctx, samplesKey, samplesBatch).Result()
+	if err == redis.Nil {
+		return samples, nil
+	}
+	if err != nil {
+		return samples, err
+	}
+
+	for _, e := range entries {
+		parts := strings.Split(e, ":")
+		if len(parts) != 3 {
+			continue
+		}
+
+		id, err1 := strconv.Atoi(parts[0])
+		viewers, err2 := strconv.Atoi(parts[1])
+		at, err3 := strconv.ParseInt(parts[2], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		samples.Ids = append(samples.Ids, int32(id))
+		samples.SampledAt = append(samples.SampledAt, pgtype.Timestamptz{Time: time.Unix(at, 0), Valid: true})
+		samples.Viewers = append(samples.Viewers, int32(viewers))
+	}
+
+	return samples, nil
+}
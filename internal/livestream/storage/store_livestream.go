@@ -53,7 +53,8 @@ func (r *livestreamStore) list(ctx context.Context, ids []int) ([]d.Livestream,
 func (r *livestreamStore) get(ctx context.Context, lsId int) (*d.Livestream, error) {
 	var ls d.Livestream
 
-	err := r.rdb.HGetAll(ctx, r.key(lsId)).Scan(&ls)
+	cmd := r.rdb.HGetAll(ctx, r.key(lsId))
+	res, err := cmd.Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, d.ErrNotFound
@@ -62,6 +63,16 @@ func (r *livestreamStore) get(ctx context.Context, lsId int) (*d.Livestream, err
 		return nil, err
 	}
 
+	// HGETALL on a missing key is an empty hash, not redis.Nil
+	if len(res) == 0 {
+		return nil, d.ErrNotFound
+	}
+
+	err = cmd.Scan(&ls)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ls, nil
 }
 
@@ -69,8 +80,16 @@ func (r *livestreamStore) exists(ctx context.Context, id int) (int64, error) {
 	return r.rdb.Exists(ctx, r.key(id)).Result()
 }
 
-func (r *livestreamStore) updateViewers(ctx context.Context, id int, viewers int) error {
-	return r.rdb.HSet(ctx, r.key(id), "viewers", viewers).Err()
+// sets current viewers and accumulates the sample into peak/sum/samples fields
+func (r *livestreamStore) updateViewersTx(ctx context.Context, tx redis.Pipeliner, ls *d.Livestream, viewers int) {
+	key := r.key(ls.Id)
+
+	tx.HSet(ctx, key, "viewers", viewers)
+	tx.HIncrBy(ctx, key, "viewers_sum", int64(viewers))
+	tx.HIncrBy(ctx, key, "viewers_samples", 1)
+	if viewers > ls.PeakViewers {
+		tx.HSet(ctx, key, "peak_viewers", viewers)
+	}
 }
 
 func (r *livestreamStore) updateFieldTx(ctx context.Context, tx redis.Pipeliner, id int, values map[string]any) error {
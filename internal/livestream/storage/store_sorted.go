@@ -40,8 +40,8 @@ func (r *sortedIDStore) addTx(ctx context.Context, tx redis.Pipeliner, categoryL
 	})
 }
 
-func (r *sortedIDStore) deleteTx(ctx context.Context, tx redis.Pipeliner, categoryLink string) *redis.IntCmd {
-	return tx.Del(ctx, r.key(categoryLink))
+func (r *sortedIDStore) deleteTx(ctx context.Context, tx redis.Pipeliner, categoryLink string, id int) *redis.IntCmd {
+	return tx.ZRem(ctx, r.key(categoryLink), id)
 }
 
 func (r *sortedIDStore) key(category string) string {
@@ -72,6 +72,11 @@ func (r *sortedIDAllStore) get(ctx context.Context, page, count int) ([]int, err
 	return ids, nil
 }
 
+// all livestream ids with their viewers
+func (r *sortedIDAllStore) getAll(ctx context.Context) ([]redis.Z, error) {
+	return r.rdb.ZRangeWithScores(ctx, r.key(), 0, -1).Result()
+}
+
 func (r *sortedIDAllStore) addTx(ctx context.Context, tx redis.Pipeliner, score int, id int) *redis.IntCmd {
 	return tx.ZAdd(ctx, r.key(), redis.Z{
 		Score:  float64(score),
@@ -79,8 +84,8 @@ func (r *sortedIDAllStore) addTx(ctx context.Context, tx redis.Pipeliner, score
 	})
 }
 
-func (r *sortedIDAllStore) deleteTx(ctx context.Context, tx redis.Pipeliner) *redis.IntCmd {
-	return tx.Del(ctx, r.key())
+func (r *sortedIDAllStore) deleteTx(ctx context.Context, tx redis.Pipeliner, id int) *redis.IntCmd {
+	return tx.ZRem(ctx, r.key(), id)
 }
 
 func (r *sortedIDAllStore) key() string {
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -25,6 +26,40 @@ func (r *userToIdStore) get(ctx context.Context, username string) (int, error) {
 	return res, nil
 }
 
+// ids of livestreams of the users in one round trip, users who are not live are left out
+func (r *userToIdStore) getMany(ctx context.Context, usernames []string) ([]int, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(usernames))
+	for i, username := range usernames {
+		keys[i] = r.key(username)
+	}
+
+	res, err := r.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(res))
+	for _, v := range res {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 func (r *userToIdStore) deleteTx(ctx context.Context, tx redis.Pipeliner, username string) *redis.IntCmd {
 	return tx.Del(ctx, r.key(username))
 }
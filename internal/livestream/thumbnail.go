@@ -0,0 +1,52 @@
+package livestream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"twitchy-api/internal/lib/handler"
+	d "twitchy-api/internal/livestream/domain"
+)
+
+type ThumbnailGetter interface {
+	Thumbnail(ctx context.Context, lsId int, name string) ([]byte, error)
+}
+
+// serves thumbnails from the shared store under the urls the updater gives out
+// ("livestreamthumbs/<livestream id>/<name>.png" under /static/)
+type ThumbnailHandler struct {
+	s   ThumbnailGetter
+	log *slog.Logger
+}
+
+func NewThumbnailHandler(log *slog.Logger, s ThumbnailGetter) *ThumbnailHandler {
+	return &ThumbnailHandler{s: s, log: log}
+}
+
+func (h *ThumbnailHandler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "getting livestream thumbnail"
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusNotFound, d.ErrThumbnailNotFound.Error())
+		return
+	}
+
+	img, err := h.s.Thumbnail(r.Context(), id, r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, d.ErrThumbnailNotFound) {
+			handler.Error(h.log, w, op, err, http.StatusNotFound, d.ErrThumbnailNotFound.Error())
+			return
+		}
+
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	// the name is the hash of the image, so it never changes under the same url
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Write(img) // nolint:errcheck
+}
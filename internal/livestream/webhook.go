@@ -0,0 +1,103 @@
+package livestream
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"twitchy-api/internal/external/streamserver"
+	"twitchy-api/internal/lib/handler"
+	d "twitchy-api/internal/livestream/domain"
+)
+
+const (
+	actionPublish   = "on_publish"
+	actionUnpublish = "on_unpublish"
+)
+
+type StartEnder interface {
+	Start(ctx context.Context, username string) error
+	End(ctx context.Context, username string) error
+}
+
+// handles srs http hooks (see deploy/srs.conf). srs can't sign its requests,
+// so the shared secret is passed in the "secret" query parameter of the hook url
+type WebhookHandler struct {
+	s      StartEnder
+	log    *slog.Logger
+	secret string
+}
+
+// every request is rejected if secret is empty
+func NewWebhookHandler(log *slog.Logger, s StartEnder, secret string) *WebhookHandler {
+	return &WebhookHandler{s: s, log: log, secret: secret}
+}
+
+// Handle godoc
+//
+//	@Summary		Stream server webhook
+//	@Description	Receives on_publish/on_unpublish events from the stream server. Responds with 0 on success as SRS expects.
+//	@Tags			Webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			secret	query		string							true	"Shared webhook secret"
+//	@Param			request	body		streamserver.StreamEventPayload	true	"Stream event"
+//	@Success		200		{integer}	int								"0"
+//	@Failure		400		{object}	handler.ErrorResponse			"Invalid request"
+//	@Failure		401		{object}	handler.ErrorResponse			"Invalid secret"
+//	@Failure		500		{object}	handler.ErrorResponse			"Internal server error"
+//	@Router			/webhooks/livestreams [post]
+func (h *WebhookHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	const op = "handling stream server event"
+
+	if !h.authorized(r) {
+		handler.Error(h.log, w, op, d.ErrWebhookSecret, http.StatusUnauthorized, d.ErrWebhookSecret.Error())
+		return
+	}
+
+	var p streamserver.StreamEventPayload
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	if p.Stream == "" {
+		handler.Error(h.log, w, op, d.ErrNoChannel, http.StatusBadRequest, d.ErrNoChannel.Error())
+		return
+	}
+
+	h.log.Debug("stream server event",
+		slog.String("action", p.Action),
+		slog.String("channel", p.Stream),
+		slog.String("client_id", p.ClientID))
+
+	ctx := r.Context()
+
+	switch p.Action {
+	case actionPublish:
+		err = h.s.Start(ctx, p.Stream)
+	case actionUnpublish:
+		err = h.s.End(ctx, p.Stream)
+	default:
+		h.log.Debug("ignoring stream server event", slog.String("action", p.Action))
+	}
+
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+		return
+	}
+
+	// srs treats any response other than 0 as an error (and rejects publishing for on_publish)
+	w.Write([]byte("0")) // nolint:errcheck
+}
+
+func (h *WebhookHandler) authorized(r *http.Request) bool {
+	if h.secret == "" {
+		return false
+	}
+
+	secret := r.URL.Query().Get("secret")
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(h.secret)) == 1
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "errors"
+
+var (
+	ErrChannelNotFound  = errors.New("channel not found")
+	ErrTierNotFound     = errors.New("subscription tier not found")
+	ErrTierExists       = errors.New("subscription tier with this name already exists")
+	ErrTierInUse        = errors.New("subscription tier has subscribers")
+	ErrTierName         = errors.New("tier name must be 1 to 32 characters long")
+	ErrTierPrice        = errors.New("tier price must be positive")
+	ErrNoTier           = errors.New("tier_id is not present in the request")
+	ErrNotSubscribed    = errors.New("not subscribed")
+	ErrSelfSubscribe    = errors.New("can't subscribe to own channel")
+	ErrPaymentDeclined  = errors.New("payment declined")
+	ErrConcurrentChange = errors.New("subscription was changed concurrently, try again")
+)
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+const (
+	// paid period of a subscription
+	Period = 30 * 24 * time.Hour
+	// how long a renewing subscription stays active after its period is over while the renewal is charged.
+	// the subscription queries hardcode the same interval
+	RenewalGrace = 3 * 24 * time.Hour
+)
+
+type Tier struct {
+	Id         int32
+	Name       string
+	PriceCents int
+	CreatedAt  time.Time
+}
+
+type TierCreate struct {
+	Channel    string
+	Name       string
+	PriceCents int
+}
+
+// active subscription of a user to a channel
+type Subscription struct {
+	TierId     int32
+	TierName   string
+	PriceCents int
+	// tier the subscription switches to on the next renewal, 0 if it keeps the current one
+	NextTierId     int32
+	SubscribedFrom time.Time
+	PeriodEnd      time.Time
+	Months         int
+	// false once cancelled, the subscription stays active until PeriodEnd
+	AutoRenew bool
+}
+
+type SubscriptionCreate struct {
+	UserId  int32
+	Channel string
+	TierId  int32
+}
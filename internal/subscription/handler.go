@@ -0,0 +1,294 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"twitchy-api/internal/app/auth"
+	"twitchy-api/internal/lib/handler"
+	d "twitchy-api/internal/subscription/domain"
+	api "twitchy-api/pkg/api/subscription"
+	"unicode/utf8"
+)
+
+type Service interface {
+	Tiers(ctx context.Context, channel string) ([]d.Tier, error)
+	CreateTier(ctx context.Context, tc d.TierCreate) (*d.Tier, error)
+	DeleteTier(ctx context.Context, channel string, id int32) error
+	Get(ctx context.Context, userId int32, channel string) (*d.Subscription, error)
+	Subscribe(ctx context.Context, sc d.SubscriptionCreate) (*d.Subscription, error)
+	Cancel(ctx context.Context, userId int32, channel string) error
+}
+
+type Handler struct {
+	s   Service
+	log *slog.Logger
+}
+
+func NewHandler(log *slog.Logger, s Service) *Handler {
+	return &Handler{s: s, log: log}
+}
+
+// ListTiers godoc
+//
+//	@Summary		List subscription tiers
+//	@Description	Subscription tiers of the channel, cheapest first
+//	@Tags			Subscriptions
+//	@Produce		json
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Success		200		{object}	api.ListTiersResponse	"Tiers"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel not found"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/subscription/tiers [get]
+func (h *Handler) ListTiers(w http.ResponseWriter, r *http.Request) {
+	const op = "listing subscription tiers"
+
+	tiers, err := h.s.Tiers(r.Context(), r.PathValue("channel"))
+	if err != nil {
+		h.error(w, op, err)
+		return
+	}
+
+	res := make([]api.Tier, len(tiers))
+	for i, t := range tiers {
+		res[i] = api.Tier(t)
+	}
+
+	json.NewEncoder(w).Encode(api.ListTiersResponse{Tiers: res})
+}
+
+// PostTier godoc
+//
+//	@Summary		Create subscription tier
+//	@Description	Add a subscription tier to the channel (owner or admin only)
+//	@Tags			Subscriptions
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Param			request	body		api.PostTierRequest		true	"Tier"
+//	@Success		201		{object}	api.PostTierResponse	"Created tier"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid name or price"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to manage subscriptions"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel not found"
+//	@Failure		409		{object}	handler.ErrorResponse	"Tier with this name already exists"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/subscription/tiers [post]
+func (h *Handler) PostTier(w http.ResponseWriter, r *http.Request) {
+	const op = "creating subscription tier"
+
+	var req api.PostTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	errs := make(map[string]error)
+
+	if n := utf8.RuneCountInString(req.Name); n == 0 || n > 32 {
+		errs["name"] = d.ErrTierName
+	}
+
+	if req.PriceCents <= 0 {
+		errs["price_cents"] = d.ErrTierPrice
+	}
+
+	if len(errs) != 0 {
+		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
+		return
+	}
+
+	tier, err := h.s.CreateTier(r.Context(), d.TierCreate{
+		Channel:    r.PathValue("channel"),
+		Name:       req.Name,
+		PriceCents: req.PriceCents,
+	})
+	if err != nil {
+		h.error(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(api.PostTierResponse{Tier: api.Tier(*tier)})
+}
+
+// DeleteTier godoc
+//
+//	@Summary		Delete subscription tier
+//	@Description	Delete a subscription tier without subscribers (owner or admin only)
+//	@Tags			Subscriptions
+//	@Security		BearerAuth
+//	@Param			channel	path		string	true	"Channel identifier"
+//	@Param			id		path		int		true	"Tier ID"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid ID"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to manage subscriptions"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel or tier not found"
+//	@Failure		409		{object}	handler.ErrorResponse	"Tier has subscribers"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/subscription/tiers/{id} [delete]
+func (h *Handler) DeleteTier(w http.ResponseWriter, r *http.Request) {
+	const op = "deleting subscription tier"
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	err = h.s.DeleteTier(r.Context(), r.PathValue("channel"), int32(id))
+	if err != nil {
+		h.error(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get godoc
+//
+//	@Summary		Get own subscription
+//	@Description	Active subscription of the authenticated user to the channel
+//	@Tags			Subscriptions
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Success		200		{object}	api.GetResponse			"Subscription"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel not found or not subscribed"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/subscription [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "getting subscription"
+
+	ctx := r.Context()
+	user, ok := auth.FromContext(ctx)
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	sub, err := h.s.Get(ctx, user.Id, r.PathValue("channel"))
+	if err != nil {
+		h.error(w, op, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(api.GetResponse(*sub))
+}
+
+// Post godoc
+//
+//	@Summary		Subscribe to channel
+//	@Description	Charge the first month and subscribe. Subscribing while subscribed resumes a cancelled subscription and changes the tier. Upgrades charge the price difference and apply at once, downgrades apply from the next renewal
+//	@Tags			Subscriptions
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			channel	path		string					true	"Channel identifier"
+//	@Param			request	body		api.PostRequest			true	"Tier to subscribe with"
+//	@Success		200		{object}	api.PostResponse		"Subscription"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request or subscribing to own channel"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		402		{object}	handler.ErrorResponse	"Payment declined"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel or tier not found"
+//	@Failure		409		{object}	handler.ErrorResponse	"Subscription changed concurrently"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/subscription [post]
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	const op = "subscribing"
+
+	ctx := r.Context()
+	user, ok := auth.FromContext(ctx)
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	var req api.PostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	if req.TierId == 0 {
+		handler.Error(h.log, w, op, d.ErrNoTier, http.StatusBadRequest, d.ErrNoTier.Error())
+		return
+	}
+
+	sub, err := h.s.Subscribe(ctx, d.SubscriptionCreate{
+		UserId:  user.Id,
+		Channel: r.PathValue("channel"),
+		TierId:  req.TierId,
+	})
+	if err != nil {
+		h.error(w, op, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(api.PostResponse(*sub))
+}
+
+// Delete godoc
+//
+//	@Summary		Cancel subscription
+//	@Description	Stop renewing the subscription, it stays active until the end of the paid period
+//	@Tags			Subscriptions
+//	@Security		BearerAuth
+//	@Param			channel	path		string	true	"Channel identifier"
+//	@Success		204		{object}	nil
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		404		{object}	handler.ErrorResponse	"Channel not found or not subscribed"
+//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
+//	@Router			/channels/{channel}/subscription [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "cancelling subscription"
+
+	ctx := r.Context()
+	user, ok := auth.FromContext(ctx)
+	if !ok {
+		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
+		return
+	}
+
+	err := h.s.Cancel(ctx, user.Id, r.PathValue("channel"))
+	if err != nil {
+		h.error(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) error(w http.ResponseWriter, op string, err error) {
+	if errors.Is(err, d.ErrChannelNotFound) ||
+		errors.Is(err, d.ErrTierNotFound) ||
+		errors.Is(err, d.ErrNotSubscribed) {
+		handler.Error(h.log, w, op, err, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrTierExists) ||
+		errors.Is(err, d.ErrTierInUse) ||
+		errors.Is(err, d.ErrConcurrentChange) {
+		handler.Error(h.log, w, op, err, http.StatusConflict, err.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrSelfSubscribe) {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrSelfSubscribe.Error())
+		return
+	}
+
+	if errors.Is(err, d.ErrPaymentDeclined) {
+		handler.Error(h.log, w, op, err, http.StatusPaymentRequired, d.ErrPaymentDeclined.Error())
+		return
+	}
+
+	handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+}
@@ -0,0 +1,71 @@
+package subscription
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	TaskRenew = "subscription:renew"
+	// every instance registers the task, the id keeps only one of them in the queue at a time
+	renewTaskID = "subscription_renew"
+
+	renewBatch = 100
+)
+
+type renewStore interface {
+	RenewDue(ctx context.Context, batch int) (int, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type scheduler interface {
+	Schedule(every time.Duration, taskType string, payload []byte, taskId string) (string, error)
+}
+
+// renews subscriptions whose paid period is over and removes cancelled ones once they expire
+type Renewer struct {
+	log   *slog.Logger
+	subs  renewStore
+	sched scheduler
+}
+
+func NewRenewer(log *slog.Logger, subs renewStore, sched scheduler) *Renewer {
+	return &Renewer{log: log, subs: subs, sched: sched}
+}
+
+// registers the periodic renewal task
+func (r *Renewer) Schedule(every time.Duration) error {
+	_, err := r.sched.Schedule(every, TaskRenew, nil, renewTaskID)
+	return err
+}
+
+func (r *Renewer) HandleRenewTask(ctx context.Context, _ []byte) error {
+	total := 0
+	for {
+		n, err := r.subs.RenewDue(ctx, renewBatch)
+		if err != nil {
+			return err
+		}
+
+		total += n
+
+		// a short batch means there is nothing left. failed renewals stay claimed and wait for a later run
+		if n < renewBatch {
+			break
+		}
+	}
+
+	expired, err := r.subs.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if total > 0 || expired > 0 {
+		r.log.Debug("subscriptions renewed",
+			slog.Int("processed", total),
+			slog.Int64("expired", expired))
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+-- name: SubscriptionSelectChannelId :one
+SELECT
+    id
+FROM
+    tc_user
+WHERE
+    name = $1;
+
+
+-- name: SubscriptionTierSelectMany :many
+SELECT
+    id,
+    id_channel,
+    name,
+    price_cents,
+    created_at
+FROM
+    tc_subscription_tier
+WHERE
+    id_channel = $1
+ORDER BY
+    price_cents, id;
+
+
+-- name: SubscriptionTierSelect :one
+SELECT
+    id,
+    id_channel,
+    name,
+    price_cents,
+    created_at
+FROM
+    tc_subscription_tier
+WHERE
+    id = $1 AND id_channel = $2;
+
+
+-- name: SubscriptionTierInsert :one
+INSERT INTO tc_subscription_tier(id_channel, name, price_cents)
+VALUES ($1, $2, $3)
+RETURNING id, id_channel, name, price_cents, created_at;
+
+
+-- name: SubscriptionTierDelete :execrows
+DELETE FROM
+    tc_subscription_tier
+WHERE
+    id = $1 AND id_channel = $2;
+
+
+-- name: SubscriptionSelect :one
+-- subscriptions stay active for 3 days (domain.RenewalGrace) after period_end
+-- while they wait for the renewal task
+SELECT
+    s.id_tier,
+    t.name AS tier_name,
+    t.price_cents,
+    s.id_tier_next,
+    s.subscribed_from,
+    s.period_end,
+    s.months,
+    s.auto_renew
+FROM
+    tc_user_subscriber s
+INNER JOIN
+    tc_subscription_tier t ON t.id = s.id_tier
+WHERE
+    s.id_user = $1 AND s.id_channel = $2
+AND (s.period_end > now() OR (s.auto_renew AND s.period_end > now() - interval '3 days'));
+
+
+-- name: SubscriptionSelectChannels :many
+SELECT
+    id_channel
+FROM
+    tc_user_subscriber
+WHERE
+    id_user = $1
+AND id_channel = ANY($2::int[])
+AND (period_end > now() OR (auto_renew AND period_end > now() - interval '3 days'));
+
+
+-- name: SubscriptionUpsert :execrows
+-- replaces only an inactive subscription
+INSERT INTO tc_user_subscriber(id_user, id_channel, id_tier, subscribed_from, period_end, months, auto_renew, payment_id)
+VALUES ($1, $2, $3, now(), $4, 1, true, $5)
+ON CONFLICT (id_user, id_channel) DO UPDATE SET
+    id_tier = EXCLUDED.id_tier,
+    id_tier_next = NULL,
+    subscribed_from = EXCLUDED.subscribed_from,
+    period_end = EXCLUDED.period_end,
+    months = EXCLUDED.months,
+    auto_renew = EXCLUDED.auto_renew,
+    payment_id = EXCLUDED.payment_id,
+    renew_claimed_at = NULL
+WHERE
+    NOT (tc_user_subscriber.period_end > now()
+    OR (tc_user_subscriber.auto_renew AND tc_user_subscriber.period_end > now() - interval '3 days'));
+
+
+-- name: SubscriptionChangeTier :execrows
+-- updates only if the period and the tier are still the ones the change was based on
+UPDATE
+    tc_user_subscriber
+SET
+    id_tier = $3,
+    id_tier_next = NULL,
+    auto_renew = true
+WHERE
+    id_user = $1 AND id_channel = $2
+AND period_end = $4 AND id_tier = $5;
+
+
+-- name: SubscriptionSetNextTier :execrows
+UPDATE
+    tc_user_subscriber
+SET
+    id_tier_next = $3,
+    auto_renew = true
+WHERE
+    id_user = $1 AND id_channel = $2
+AND period_end = $4;
+
+
+-- name: SubscriptionCancel :execrows
+UPDATE
+    tc_user_subscriber
+SET
+    auto_renew = false
+WHERE
+    id_user = $1 AND id_channel = $2
+AND (period_end > now() OR (auto_renew AND period_end > now() - interval '3 days'));
+
+
+-- name: SubscriptionClaimDue :many
+-- claims due subscriptions for renewal and returns the tier they renew with
+UPDATE
+    tc_user_subscriber s
+SET
+    renew_claimed_at = now()
+FROM
+    tc_subscription_tier t
+WHERE
+    t.id = COALESCE(s.id_tier_next, s.id_tier)
+AND (s.id_user, s.id_channel) IN (
+    SELECT
+        id_user,
+        id_channel
+    FROM
+        tc_user_subscriber
+    WHERE
+        period_end <= now() AND period_end > now() - interval '3 days' AND auto_renew
+    AND (renew_claimed_at IS NULL OR renew_claimed_at < now() - interval '5 minutes')
+    ORDER BY
+        period_end
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING
+    s.id_user,
+    s.id_channel,
+    s.period_end,
+    t.id AS id_tier,
+    t.price_cents;
+
+
+-- name: SubscriptionRenew :execrows
+-- a subscription cancelled while its renewal was charged is not renewed
+UPDATE
+    tc_user_subscriber
+SET
+    id_tier = $3,
+    id_tier_next = NULLIF(id_tier_next, $3),
+    period_end = $4,
+    months = months + 1,
+    payment_id = $5,
+    renew_claimed_at = NULL
+WHERE
+    id_user = $1 AND id_channel = $2
+AND period_end = $6 AND auto_renew;
+
+
+-- name: SubscriptionDelete :execrows
+DELETE FROM
+    tc_user_subscriber
+WHERE
+    id_user = $1 AND id_channel = $2
+AND period_end = $3;
+
+
+-- name: SubscriptionDeleteExpired :execrows
+-- cancelled subscriptions once the period is over, and renewing ones the renewal task gave up on
+DELETE FROM
+    tc_user_subscriber
+WHERE
+    period_end <= now()
+AND (NOT auto_renew OR period_end <= now() - interval '3 days');
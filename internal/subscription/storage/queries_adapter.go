@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"twitchy-api/internal/external/db"
+	d "twitchy-api/internal/subscription/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type queriesAdapter struct {
+	queries *db.Queries
+}
+
+func (q *queriesAdapter) SelectChannelId(ctx context.Context, name string) (int32, error) {
+	id, err := q.queries.SubscriptionSelectChannelId(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, d.ErrChannelNotFound
+	}
+
+	return id, err
+}
+
+func (q *queriesAdapter) SelectTiers(ctx context.Context, channelId int32) ([]db.TcSubscriptionTier, error) {
+	return q.queries.SubscriptionTierSelectMany(ctx, channelId)
+}
+
+func (q *queriesAdapter) SelectTier(ctx context.Context, arg db.SubscriptionTierSelectParams) (db.TcSubscriptionTier, error) {
+	tier, err := q.queries.SubscriptionTierSelect(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.TcSubscriptionTier{}, d.ErrTierNotFound
+	}
+
+	return tier, err
+}
+
+func (q *queriesAdapter) InsertTier(ctx context.Context, arg db.SubscriptionTierInsertParams) (db.TcSubscriptionTier, error) {
+	tier, err := q.queries.SubscriptionTierInsert(ctx, arg)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == db.CodeUniqueConstraint {
+		return db.TcSubscriptionTier{}, d.ErrTierExists
+	}
+
+	return tier, err
+}
+
+func (q *queriesAdapter) DeleteTier(ctx context.Context, arg db.SubscriptionTierDeleteParams) error {
+	n, err := q.queries.SubscriptionTierDelete(ctx, arg)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == db.CodeForeignKeyConstraint {
+		return d.ErrTierInUse
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return d.ErrTierNotFound
+	}
+
+	return nil
+}
+
+func (q *queriesAdapter) Select(ctx context.Context, arg db.SubscriptionSelectParams) (db.SubscriptionSelectRow, error) {
+	sub, err := q.queries.SubscriptionSelect(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.SubscriptionSelectRow{}, d.ErrNotSubscribed
+	}
+
+	return sub, err
+}
+
+func (q *queriesAdapter) SelectChannels(ctx context.Context, arg db.SubscriptionSelectChannelsParams) ([]int32, error) {
+	return q.queries.SubscriptionSelectChannels(ctx, arg)
+}
+
+// false if an active subscription appeared since the charge
+func (q *queriesAdapter) Upsert(ctx context.Context, arg db.SubscriptionUpsertParams) (bool, error) {
+	n, err := q.queries.SubscriptionUpsert(ctx, arg)
+	return n > 0, err
+}
+
+// false if the subscription was renewed or changed since it was read
+func (q *queriesAdapter) ChangeTier(ctx context.Context, arg db.SubscriptionChangeTierParams) (bool, error) {
+	n, err := q.queries.SubscriptionChangeTier(ctx, arg)
+	return n > 0, err
+}
+
+// false if the subscription was renewed or removed since it was read
+func (q *queriesAdapter) SetNextTier(ctx context.Context, arg db.SubscriptionSetNextTierParams) (bool, error) {
+	n, err := q.queries.SubscriptionSetNextTier(ctx, arg)
+	return n > 0, err
+}
+
+func (q *queriesAdapter) Cancel(ctx context.Context, arg db.SubscriptionCancelParams) error {
+	n, err := q.queries.SubscriptionCancel(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return d.ErrNotSubscribed
+	}
+
+	return nil
+}
+
+func (q *queriesAdapter) ClaimDue(ctx context.Context, limit int32) ([]db.SubscriptionClaimDueRow, error) {
+	return q.queries.SubscriptionClaimDue(ctx, limit)
+}
+
+// false if the period was already renewed
+func (q *queriesAdapter) Renew(ctx context.Context, arg db.SubscriptionRenewParams) (bool, error) {
+	n, err := q.queries.SubscriptionRenew(ctx, arg)
+	return n > 0, err
+}
+
+func (q *queriesAdapter) Delete(ctx context.Context, arg db.SubscriptionDeleteParams) error {
+	_, err := q.queries.SubscriptionDelete(ctx, arg)
+	return err
+}
+
+func (q *queriesAdapter) DeleteExpired(ctx context.Context) (int64, error) {
+	return q.queries.SubscriptionDeleteExpired(ctx)
+}
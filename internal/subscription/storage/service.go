@@ -0,0 +1,426 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"twitchy-api/internal/external/db"
+	"twitchy-api/internal/external/payment"
+	"twitchy-api/internal/lib/sl"
+	d "twitchy-api/internal/subscription/domain"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ServiceImpl struct {
+	log  *slog.Logger
+	pool *pgxpool.Pool
+	pp   payment.Provider
+}
+
+func NewService(log *slog.Logger, pool *pgxpool.Pool, pp payment.Provider) *ServiceImpl {
+	return &ServiceImpl{log: log, pool: pool, pp: pp}
+}
+
+func (s *ServiceImpl) Tiers(ctx context.Context, channel string) ([]d.Tier, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.SelectTiers(ctx, channelId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tiers: %w", err)
+	}
+
+	tiers := make([]d.Tier, len(rows))
+	for i, row := range rows {
+		tiers[i] = tierFromRow(row)
+	}
+
+	return tiers, nil
+}
+
+func (s *ServiceImpl) CreateTier(ctx context.Context, tc d.TierCreate) (*d.Tier, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, tc.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := q.InsertTier(ctx, db.SubscriptionTierInsertParams{
+		IDChannel:  channelId,
+		Name:       tc.Name,
+		PriceCents: int32(tc.PriceCents),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tier := tierFromRow(row)
+	return &tier, nil
+}
+
+// tiers with subscribers can't be deleted
+func (s *ServiceImpl) DeleteTier(ctx context.Context, channel string, id int32) error {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	return q.DeleteTier(ctx, db.SubscriptionTierDeleteParams{
+		ID:        id,
+		IDChannel: channelId,
+	})
+}
+
+// active subscription of the user to the channel
+func (s *ServiceImpl) Get(ctx context.Context, userId int32, channel string) (*d.Subscription, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.get(ctx, q, userId, channelId)
+}
+
+// charges the first period and starts the subscription. subscribing while already subscribed
+// resumes a cancelled subscription and changes the tier: upgrades are charged the price difference
+// and apply at once, downgrades apply from the next renewal
+func (s *ServiceImpl) Subscribe(ctx context.Context, sc d.SubscriptionCreate) (*d.Subscription, error) {
+	const op = "subscription.ServiceImpl.Subscribe"
+
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, sc.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if channelId == sc.UserId {
+		return nil, d.ErrSelfSubscribe
+	}
+
+	tier, err := q.SelectTier(ctx, db.SubscriptionTierSelectParams{
+		ID:        sc.TierId,
+		IDChannel: channelId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := q.Select(ctx, db.SubscriptionSelectParams{
+		IDUser:    sc.UserId,
+		IDChannel: channelId,
+	})
+	if err == nil {
+		err = s.changeTier(ctx, q, sc, tier, sub)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.get(ctx, q, sc.UserId, channelId)
+	}
+	if !errors.Is(err, d.ErrNotSubscribed) {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	// the reference is unique, a concurrent request is charged separately and refunded below
+	paymentId, err := s.charge(ctx, payment.Charge{
+		Reference:   fmt.Sprintf("subscribe:%d:%d:%d", sc.UserId, channelId, time.Now().UnixNano()),
+		CustomerID:  sc.UserId,
+		AmountCents: int(tier.PriceCents),
+		Description: fmt.Sprintf("%s subscription to %s", tier.Name, sc.Channel),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the charge can't be part of a transaction, so a failed write gives the money back instead
+	saved, err := q.Upsert(ctx, db.SubscriptionUpsertParams{
+		IDUser:    sc.UserId,
+		IDChannel: channelId,
+		IDTier:    tier.ID,
+		PeriodEnd: pgtype.Timestamptz{Time: time.Now().Add(d.Period), Valid: true},
+		PaymentID: pgtype.Text{String: paymentId, Valid: true},
+	})
+	if err != nil {
+		s.refund(ctx, op, paymentId)
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	if !saved {
+		s.refund(ctx, op, paymentId)
+		return nil, d.ErrConcurrentChange
+	}
+
+	return s.get(ctx, q, sc.UserId, channelId)
+}
+
+func (s *ServiceImpl) changeTier(ctx context.Context, q queriesAdapter, sc d.SubscriptionCreate, tier db.TcSubscriptionTier, sub db.SubscriptionSelectRow) error {
+	const op = "subscription.ServiceImpl.changeTier"
+
+	change := db.SubscriptionChangeTierParams{
+		IDUser:    sc.UserId,
+		IDChannel: tier.IDChannel,
+		IDTier:    tier.ID,
+		PeriodEnd: sub.PeriodEnd,
+		IDTier_2:  sub.IDTier,
+	}
+
+	if tier.ID == sub.IDTier {
+		ok, err := q.ChangeTier(ctx, change)
+		if err != nil {
+			return fmt.Errorf("failed to resume subscription: %w", err)
+		}
+
+		if !ok {
+			return d.ErrConcurrentChange
+		}
+
+		return nil
+	}
+
+	// a due subscription is charged by the renewal with the new tier
+	due := !sub.PeriodEnd.Time.After(time.Now())
+	if due || tier.PriceCents <= sub.PriceCents {
+		ok, err := q.SetNextTier(ctx, db.SubscriptionSetNextTierParams{
+			IDUser:     sc.UserId,
+			IDChannel:  tier.IDChannel,
+			IDTierNext: pgtype.Int4{Int32: tier.ID, Valid: true},
+			PeriodEnd:  sub.PeriodEnd,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to change subscription tier: %w", err)
+		}
+
+		if !ok {
+			return d.ErrConcurrentChange
+		}
+
+		return nil
+	}
+
+	paymentId, err := s.charge(ctx, payment.Charge{
+		Reference:   fmt.Sprintf("upgrade:%d:%d:%d", sc.UserId, tier.IDChannel, time.Now().UnixNano()),
+		CustomerID:  sc.UserId,
+		AmountCents: int(tier.PriceCents - sub.PriceCents),
+		Description: fmt.Sprintf("upgrade to %s subscription to %s", tier.Name, sc.Channel),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the update only matches the tier the difference was computed for
+	ok, err := q.ChangeTier(ctx, change)
+	if err != nil {
+		s.refund(ctx, op, paymentId)
+		return fmt.Errorf("failed to change subscription tier: %w", err)
+	}
+
+	if !ok {
+		s.refund(ctx, op, paymentId)
+		return d.ErrConcurrentChange
+	}
+
+	return nil
+}
+
+// stops renewal, the subscription stays active until the end of the paid period
+func (s *ServiceImpl) Cancel(ctx context.Context, userId int32, channel string) error {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	channelId, err := q.SelectChannelId(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	return q.Cancel(ctx, db.SubscriptionCancelParams{
+		IDUser:    userId,
+		IDChannel: channelId,
+	})
+}
+
+// which of the channels the user is actively subscribed to
+func (s *ServiceImpl) SubscribedChannels(ctx context.Context, userId int32, channelIds []int32) (map[int32]bool, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	ids, err := q.SelectChannels(ctx, db.SubscriptionSelectChannelsParams{
+		IDUser:  userId,
+		Column2: channelIds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribed channels: %w", err)
+	}
+
+	subscribed := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		subscribed[id] = true
+	}
+
+	return subscribed, nil
+}
+
+// charges up to batch subscriptions whose period is over and returns how many were claimed.
+// the payment provider is called outside of any transaction: claimed subscriptions are skipped by
+// other runs for a few minutes, and a renewal that failed midway is retried once the claim expires.
+// declined ones are removed
+func (s *ServiceImpl) RenewDue(ctx context.Context, batch int) (int, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	due, err := q.ClaimDue(ctx, int32(batch))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due subscriptions: %w", err)
+	}
+
+	for _, sub := range due {
+		s.renew(ctx, q, sub)
+	}
+
+	return len(due), nil
+}
+
+func (s *ServiceImpl) renew(ctx context.Context, q queriesAdapter, sub db.SubscriptionClaimDueRow) {
+	const op = "subscription.ServiceImpl.renew"
+
+	log := s.log.With(sl.Op(op),
+		slog.Int("user_id", int(sub.IDUser)),
+		slog.Int("channel_id", int(sub.IDChannel)))
+
+	// the reference is bound to the period, so a retried renewal isn't charged twice
+	paymentId, err := s.pp.Charge(ctx, payment.Charge{
+		Reference:   fmt.Sprintf("renew:%d:%d:%d", sub.IDUser, sub.IDChannel, sub.PeriodEnd.Time.Unix()),
+		CustomerID:  sub.IDUser,
+		AmountCents: int(sub.PriceCents),
+		Description: "subscription renewal",
+	})
+	if errors.Is(err, payment.ErrDeclined) {
+		err = q.Delete(ctx, db.SubscriptionDeleteParams{
+			IDUser:    sub.IDUser,
+			IDChannel: sub.IDChannel,
+			PeriodEnd: sub.PeriodEnd,
+		})
+		if err != nil {
+			log.Error("expiring subscription", sl.Err(err))
+		}
+
+		return
+	}
+	if err != nil {
+		log.Error("charging renewal", sl.Err(err))
+		return
+	}
+
+	// periods missed while renewals weren't running are not charged retroactively
+	periodEnd := sub.PeriodEnd.Time.Add(d.Period)
+	if periodEnd.Before(time.Now()) {
+		periodEnd = time.Now().Add(d.Period)
+	}
+
+	renewed, err := q.Renew(ctx, db.SubscriptionRenewParams{
+		IDUser:      sub.IDUser,
+		IDChannel:   sub.IDChannel,
+		IDTier:      sub.IDTier,
+		PeriodEnd:   pgtype.Timestamptz{Time: periodEnd, Valid: true},
+		PaymentID:   pgtype.Text{String: paymentId, Valid: true},
+		PeriodEnd_2: sub.PeriodEnd,
+	})
+	if err != nil {
+		// the retry gets the same payment back from the provider, so nothing is refunded
+		log.Error("saving renewal", sl.Err(err))
+		return
+	}
+
+	if renewed {
+		return
+	}
+
+	// a run that took over an expired claim may have renewed the period with the same payment
+	cur, err := q.Select(ctx, db.SubscriptionSelectParams{
+		IDUser:    sub.IDUser,
+		IDChannel: sub.IDChannel,
+	})
+	if err == nil && cur.PeriodEnd.Time.After(sub.PeriodEnd.Time) {
+		return
+	}
+	if err != nil && !errors.Is(err, d.ErrNotSubscribed) {
+		log.Error("checking renewal", sl.Err(err))
+		return
+	}
+
+	// cancelled or removed while the renewal was charged
+	s.refund(ctx, op, paymentId)
+}
+
+// removes cancelled subscriptions whose period is over and renewing ones whose renewal failed for the whole grace period
+func (s *ServiceImpl) DeleteExpired(ctx context.Context) (int64, error) {
+	q := queriesAdapter{queries: db.New(s.pool)}
+
+	n, err := q.DeleteExpired(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired subscriptions: %w", err)
+	}
+
+	return n, nil
+}
+
+func (s *ServiceImpl) get(ctx context.Context, q queriesAdapter, userId, channelId int32) (*d.Subscription, error) {
+	row, err := q.Select(ctx, db.SubscriptionSelectParams{
+		IDUser:    userId,
+		IDChannel: channelId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &d.Subscription{
+		TierId:         row.IDTier,
+		TierName:       row.TierName,
+		PriceCents:     int(row.PriceCents),
+		NextTierId:     row.IDTierNext.Int32,
+		SubscribedFrom: row.SubscribedFrom.Time,
+		PeriodEnd:      row.PeriodEnd.Time,
+		Months:         int(row.Months),
+		AutoRenew:      row.AutoRenew,
+	}, nil
+}
+
+func (s *ServiceImpl) charge(ctx context.Context, ch payment.Charge) (string, error) {
+	paymentId, err := s.pp.Charge(ctx, ch)
+	if errors.Is(err, payment.ErrDeclined) {
+		return "", d.ErrPaymentDeclined
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to charge subscription: %w", err)
+	}
+
+	return paymentId, nil
+}
+
+// refunds a charge that didn't end up in the database. failures are only logged,
+// the payment id is in the log for a manual refund
+func (s *ServiceImpl) refund(ctx context.Context, op, paymentId string) {
+	err := s.pp.Refund(context.WithoutCancel(ctx), paymentId)
+	if err != nil {
+		s.log.Error("refunding charge", sl.Err(err), sl.Op(op), slog.String("payment_id", paymentId))
+	}
+}
+
+func tierFromRow(row db.TcSubscriptionTier) d.Tier {
+	return d.Tier{
+		Id:         row.ID,
+		Name:       row.Name,
+		PriceCents: int(row.PriceCents),
+		CreatedAt:  row.CreatedAt.Time,
+	}
+}
@@ -19,12 +19,6 @@ type User struct {
 	Tags            []string
 }
 
-type UserCreate struct {
-	Name     string
-	Password string
-	Pfp      null.String
-}
-
 type UserUpdate struct {
 	Name      null.String
 	Password  null.String
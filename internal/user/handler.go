@@ -8,15 +8,15 @@ import (
 	"net/http"
 	"strconv"
 	"twitchy-api/internal/app/auth"
+	"twitchy-api/internal/app/authz"
 	"twitchy-api/internal/lib/handler"
+	"twitchy-api/internal/lib/password"
 	d "twitchy-api/internal/user/domain"
 	api "twitchy-api/pkg/api/user"
-	"unicode/utf8"
 )
 
 type Repository interface {
 	Get(ctx context.Context, id int32) (*d.User, error)
-	Create(ctx context.Context, u d.UserCreate) error
 	Update(ctx context.Context, id int32, upd d.UserUpdate) error
 	Delete(ctx context.Context, id int32) error
 	List(ctx context.Context, l d.UserList) ([]d.User, error)
@@ -24,11 +24,12 @@ type Repository interface {
 
 type Handler struct {
 	s   Repository
+	az  *authz.Authorizer
 	log *slog.Logger
 }
 
-func NewHandler(log *slog.Logger, s Repository) *Handler {
-	return &Handler{s: s, log: log}
+func NewHandler(log *slog.Logger, s Repository, az *authz.Authorizer) *Handler {
+	return &Handler{s: s, az: az, log: log}
 }
 
 // Get godoc
@@ -75,63 +76,6 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Post godoc
-//
-//	@Summary		Create user account
-//	@Description	Register new user account
-//	@Tags			Users
-//	@Accept			json
-//	@Param			request	body		api.PostRequest	true	"User creation data"
-//	@Success		204		{object}	nil
-//	@Failure		400		{object}	handler.ErrorResponse	"Invalid request, missing name/password, weak password"
-//	@Failure		409		{object}	handler.ErrorResponse	"User already exists"
-//	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
-//	@Router			/users [post]
-func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
-	const op = "creating user"
-
-	var req api.PostRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
-		return
-	}
-
-	errs := make(map[string]error)
-
-	if req.Name == "" {
-		errs["name"] = d.ErrUsernameRequired
-	}
-
-	if req.Password == "" {
-		errs["password"] = d.ErrPasswordRequired
-	}
-
-	if req.Password != "" && utf8.RuneCountInString(req.Password) < 8 {
-		errs["password"] = d.ErrWeakPassword
-	}
-
-	if len(errs) != 0 {
-		handler.Errors(h.log, w, op, http.StatusBadRequest, errs)
-		return
-	}
-
-	if err := h.s.Create(r.Context(), d.UserCreate{
-		Name:     req.Name,
-		Password: req.Password,
-		Pfp:      req.Pfp,
-	}); err != nil {
-		if errors.Is(err, d.ErrAlreadyExists) {
-			handler.Error(h.log, w, op, err, http.StatusConflict, d.ErrAlreadyExists.Error())
-			return
-		}
-
-		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
-
 // Patch godoc
 //
 //	@Summary		Update user
@@ -142,7 +86,9 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 //	@Param			id		path		int					true	"User ID"
 //	@Param			request	body		api.PatchRequest	true	"Update data (name, password, avatar, banned, partner)"
 //	@Success		204		{object}	nil
-//	@Failure		400		{object}	handler.ErrorResponse	"Invalid ID, claims, identity mismatch, request, or weak password"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid ID, request, or weak password"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to edit the user or set banned and partner flags"
 //	@Failure		409		{object}	handler.ErrorResponse	"Name already exists"
 //	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
 //	@Router			/users/{id} [patch]
@@ -156,24 +102,32 @@ func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	user, ok := auth.FromContext(ctx)
-	if !ok {
-		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusBadRequest, handler.MsgIdentity)
+	var req api.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
 		return
 	}
 
-	if user.Role != auth.RoleStaff {
-		if user.Id != int32(idInt) {
-			handler.Error(h.log, w, op, handler.ErrIdentity, http.StatusBadRequest, handler.MsgIdentity)
+	ctx := r.Context()
+
+	// editing own profile is allowed by the route, flags are not
+	if req.IsBanned.Explicit || req.IsPartner.Explicit {
+		user, ok := auth.FromContext(ctx)
+		if !ok {
+			handler.Error(h.log, w, op, handler.ErrClaims, http.StatusUnauthorized, handler.MsgIdentity)
 			return
 		}
-	}
 
-	var req api.PatchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
-		return
+		allowed, err := h.az.Can(ctx, user, authz.UsersManage, authz.Target{UserID: int32(idInt)})
+		if err != nil {
+			handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
+			return
+		}
+
+		if !allowed {
+			handler.Error(h.log, w, op, handler.ErrNotAllowed, http.StatusForbidden, handler.ErrNotAllowed.Error())
+			return
+		}
 	}
 
 	if err := h.s.Update(ctx, int32(idInt), d.UserUpdate{
@@ -188,6 +142,11 @@ func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, password.ErrTooLong) {
+			handler.Error(h.log, w, op, err, http.StatusBadRequest, password.ErrTooLong.Error())
+			return
+		}
+
 		if errors.Is(err, d.ErrWeakPassword) {
 			handler.Error(h.log, w, op, err, http.StatusBadRequest, d.ErrWeakPassword.Error())
 			return
@@ -203,14 +162,16 @@ func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 // Delete godoc
 //
 //	@Summary		Delete user account
-//	@Description	Delete own user account (self only)
+//	@Description	Delete own user account (self or staff only), body id must match path id
 //	@Tags			Users
 //	@Accept			json
 //	@Security		BearerAuth
 //	@Param			id		path		int					true	"User ID"
 //	@Param			request	body		api.DeleteRequest	true	"Delete confirmation"
 //	@Success		204		{object}	nil
-//	@Failure		400		{object}	handler.ErrorResponse	"Invalid ID, claims, identity mismatch, or request"
+//	@Failure		400		{object}	handler.ErrorResponse	"Invalid ID, request, or id mismatch"
+//	@Failure		401		{object}	handler.ErrorResponse	"Not authenticated"
+//	@Failure		403		{object}	handler.ErrorResponse	"Not allowed to delete the user"
 //	@Failure		500		{object}	handler.ErrorResponse	"Internal server error"
 //	@Router			/users/{id} [delete]
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -223,27 +184,19 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	user, ok := auth.FromContext(ctx)
-	if !ok {
-		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
-	if user.Role != auth.RoleStaff {
-		if user.Id != int32(idInt) {
-			handler.Error(h.log, w, op, handler.ErrIdentity, http.StatusBadRequest, handler.MsgIdentity)
-			return
-		}
-	}
-
 	var req api.DeleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
 		return
 	}
 
-	if err := h.s.Delete(ctx, int32(req.Id)); err != nil {
+	// the route authorizes the path id, so the body must not point to someone else
+	if req.Id != idInt {
+		handler.Error(h.log, w, op, handler.ErrIdentity, http.StatusBadRequest, handler.MsgRequest)
+		return
+	}
+
+	if err := h.s.Delete(r.Context(), int32(idInt)); err != nil {
 		handler.Error(h.log, w, op, err, http.StatusInternalServerError, handler.MsgInternal)
 		return
 	}
@@ -254,18 +207,6 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	const op = "list users"
 
-	ctx := r.Context()
-	user, ok := auth.FromContext(ctx)
-	if !ok {
-		handler.Error(h.log, w, op, handler.ErrClaims, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
-	if user.Role != auth.RoleStaff {
-		handler.Error(h.log, w, op, handler.ErrNotAllowed, http.StatusBadRequest, handler.MsgIdentity)
-		return
-	}
-
 	// var req api.ListRequest
 	// if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 	// 	handler.Error(h.log, w, op, err, http.StatusBadRequest, handler.MsgRequest)
@@ -32,11 +32,15 @@ WHERE
 INSERT INTO tc_user (
     name,
     password,
+    password_algo,
+    email,
     pfp)
 VALUES (
     $1,
     $2,
-    $3)
+    $3,
+    $4,
+    $5)
 RETURNING id;
 
 
@@ -46,6 +50,7 @@ UPDATE
 SET
     name       = CASE WHEN @name_do_update::boolean THEN @name ELSE name END,
     password   = CASE WHEN @password_do_update::boolean THEN @password ELSE password END,
+    password_algo = CASE WHEN @password_do_update::boolean THEN @password_algo ELSE password_algo END,
     is_banned  = CASE WHEN @is_banned_do_update::boolean THEN @is_banned ELSE is_banned END,
     is_partner = CASE WHEN @is_partner_do_update::boolean THEN @is_partner ELSE is_partner END,
     pfp        = CASE WHEN @pfp_do_update::boolean THEN @pfp ELSE pfp END,
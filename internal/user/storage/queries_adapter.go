@@ -42,23 +42,6 @@ func (q *queriesAdapter) SelectByUsername(ctx context.Context, username string)
 	return res, err
 }
 
-func (q *queriesAdapter) Insert(ctx context.Context, p db.UserInsertParams) error {
-	_, err := q.queries.UserInsert(ctx, p)
-
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == db.CodeUniqueConstraint {
-				return d.ErrAlreadyExists
-			}
-		}
-
-		return err
-	}
-
-	return err
-}
-
 func (q *queriesAdapter) Update(ctx context.Context, arg db.UserUpdateParams) error {
 	err := q.queries.UserUpdate(ctx, arg)
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"twitchy-api/internal/external/db"
+	"twitchy-api/internal/lib/password"
 	d "twitchy-api/internal/user/domain"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -36,18 +37,33 @@ func (r *RepositoryImpl) Get(ctx context.Context, id int32) (*d.User, error) {
 		Pfp:             res.Pfp.String}, nil
 }
 
-func (r *RepositoryImpl) Create(ctx context.Context, u d.UserCreate) error {
+// id of the user, false if there is no such user
+func (r *RepositoryImpl) IdByUsername(ctx context.Context, username string) (int32, bool, error) {
 	q := queriesAdapter{queries: db.New(r.pool)}
 
-	err := q.Insert(ctx, db.UserInsertParams{
-		Name:     u.Name,
-		Password: u.Password,
-		Pfp:      pgtype.Text{String: u.Pfp.Value, Valid: u.Pfp.Explicit && !u.Pfp.IsNull}})
+	res, err := q.SelectByUsername(ctx, username)
+	if errors.Is(err, d.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
 
-	return err
+	return res.ID, true, nil
 }
 
 func (r *RepositoryImpl) Update(ctx context.Context, id int32, upd d.UserUpdate) error {
+	passwordDoUpdate := upd.Password.Explicit && !upd.Password.IsNull
+
+	var hash string
+	if passwordDoUpdate {
+		var err error
+		hash, err = password.Hash(upd.Password.Value)
+		if err != nil {
+			return err
+		}
+	}
+
 	q := queriesAdapter{queries: db.New(r.pool)}
 
 	err := q.Update(ctx, db.UserUpdateParams{
@@ -56,8 +72,9 @@ func (r *RepositoryImpl) Update(ctx context.Context, id int32, upd d.UserUpdate)
 		NameDoUpdate: upd.Name.Explicit && !upd.Name.IsNull,
 		Name:         upd.Name.Value,
 
-		PasswordDoUpdate: upd.Password.Explicit && !upd.Password.IsNull,
-		Password:         upd.Password.Value,
+		PasswordDoUpdate: passwordDoUpdate,
+		Password:         hash,
+		PasswordAlgo:     password.AlgoCurrent,
 
 		IsBannedDoUpdate: upd.IsBanned.Explicit && !upd.IsBanned.IsNull,
 		IsBanned:         pgtype.Bool{Bool: upd.IsBanned.Value, Valid: true},
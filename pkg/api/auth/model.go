@@ -9,10 +9,30 @@ type LoginResponse struct {
 }
 
 type RegisterRequest struct {
+	// optional, verification link is sent to it
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// optional
+	Pfp string `json:"pfp"`
 }
 type RegisterResponse struct {
 	Access string `json:"access"`
 }
+
+type RefreshResponse struct {
+	Access string `json:"access"`
+}
+
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -22,6 +22,7 @@ type GetResponse struct {
 	LastLivestream  time.Time `json:"last_livestream"`
 	Description     string    `json:"description"`
 	Background      string    `json:"background"`
+	Followers       int64     `json:"followers"`
 	Links           []Link    `json:"links"`
 	Tags            []Tag     `json:"tags"`
 }
@@ -39,3 +40,40 @@ type PatchResponse struct{}
 
 type DeleteRequest struct{}
 type DeleteResponse struct{}
+
+type Moderator struct {
+	Id      int32     `json:"id"`
+	Name    string    `json:"name"`
+	Pfp     string    `json:"pfp"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type ListModeratorsResponse struct {
+	Moderators []Moderator `json:"moderators"`
+}
+
+type AddModeratorRequest struct {
+	Username string `json:"username"`
+}
+
+type Ban struct {
+	UserId     int32     `json:"user_id"`
+	Name       string    `json:"name"`
+	Pfp        string    `json:"pfp"`
+	BannedFrom time.Time `json:"banned_from"`
+	// null for permanent bans
+	ExpiresAt *time.Time `json:"expires_at"`
+	Reason    string     `json:"reason"`
+	BannedBy  string     `json:"banned_by"`
+}
+
+type ListBansResponse struct {
+	Bans []Ban `json:"bans"`
+}
+
+type BanRequest struct {
+	Username string `json:"username"`
+	// 0 or absent for permanent ban, timeout duration otherwise
+	DurationSeconds int    `json:"duration_seconds"`
+	Reason          string `json:"reason"`
+}
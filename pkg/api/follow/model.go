@@ -1,5 +1,7 @@
 package follow
 
+import "time"
+
 type GetRequest struct{}
 type GetResponse struct {
 	IsFollower bool `json:"is_follower"`
@@ -26,6 +28,18 @@ type ListExtendedResponseItem struct {
 	IsLive   bool   `json:"is_live"`
 }
 
+type FollowersResponse struct {
+	Followers []FollowersResponseItem `json:"followers"`
+	// pass as cursor to get the next page, omitted on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+type FollowersResponseItem struct {
+	Id            int32     `json:"id"`
+	Name          string    `json:"name"`
+	Pfp           string    `json:"pfp"`
+	FollowingFrom time.Time `json:"following_from"`
+}
+
 type PostRequest struct {
 	Follow string `json:"follow"`
 }
@@ -1,6 +1,7 @@
 package livestream
 
 import (
+	"fmt"
 	"net/http"
 	"twitchy-api/internal/lib/null"
 	baseclient "twitchy-api/pkg/api/client"
@@ -39,6 +40,16 @@ func (c *Client) List() (*http.Response, error) {
 	return c.base.Client.Do(req)
 }
 
+func (c *Client) Viewers(id int, resolution string) (*http.Response, error) {
+	req, err := c.base.Get(fmt.Sprintf("%s%d/viewers?resolution=%s", c.BaseURL, id, resolution))
+	if err != nil {
+		return nil, err
+	}
+	defer req.Body.Close() // nolint
+
+	return c.base.Client.Do(req)
+}
+
 func (c *Client) Patch(username, title string, categoryId int) (*http.Response, error) {
 	data := PatchRequest{
 		Title: null.String{
@@ -58,3 +69,25 @@ func (c *Client) Patch(username, title string, categoryId int) (*http.Response,
 
 	return c.base.Client.Do(req)
 }
+
+// client for /channels/{channel}/broadcasts, url is expected to point to /channels/
+type BroadcastClient struct {
+	base    *baseclient.Client
+	BaseURL string
+}
+
+func NewBroadcastClient(url string) *BroadcastClient {
+	return &BroadcastClient{
+		base:    baseclient.NewClient(),
+		BaseURL: url}
+}
+
+func (c *BroadcastClient) List(channel string, page, count int) (*http.Response, error) {
+	req, err := c.base.Get(fmt.Sprintf("%s%s/broadcasts?page=%d&count=%d", c.BaseURL, channel, page, count))
+	if err != nil {
+		return nil, err
+	}
+	defer req.Body.Close() // nolint
+
+	return c.base.Client.Do(req)
+}
@@ -45,10 +45,37 @@ type ListResponseItem struct {
 	Viewers   int                `json:"viewers"`
 	Channel   LivestreamChannel  `json:"channel"`
 	Category  LivestreamCategory `json:"category"`
+	// filled only for authenticated requests
+	IsFollowing  bool `json:"is_following"`
+	IsSubscriber bool `json:"is_subscriber"`
 	// IsMultistream bool               `json:"is_multistream"`
 	// IsPartner     bool   `json:"is_partner"`
 }
 
+type BroadcastListResponse struct {
+	Broadcasts []BroadcastListResponseItem `json:"broadcasts"`
+}
+type BroadcastListResponseItem struct {
+	Id          int                `json:"id"`
+	Title       string             `json:"title"`
+	StartedAt   int                `json:"started_at"`
+	EndedAt     int                `json:"ended_at"`
+	Duration    int                `json:"duration"`
+	PeakViewers int                `json:"peak_viewers"`
+	AvgViewers  int                `json:"avg_viewers"`
+	Category    LivestreamCategory `json:"category"`
+}
+
+type ViewersResponse struct {
+	Resolution string                `json:"resolution"`
+	Samples    []ViewersResponseItem `json:"samples"`
+}
+type ViewersResponseItem struct {
+	Time        int `json:"time"`
+	PeakViewers int `json:"peak_viewers"`
+	AvgViewers  int `json:"avg_viewers"`
+}
+
 type PostRequest struct {
 	Title        string `json:"title"`
 	CategoryLink string `json:"category_link"`
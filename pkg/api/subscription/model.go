@@ -0,0 +1,38 @@
+package subscription
+
+import "time"
+
+type Tier struct {
+	Id         int32     `json:"id"`
+	Name       string    `json:"name"`
+	PriceCents int       `json:"price_cents"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ListTiersResponse struct {
+	Tiers []Tier `json:"tiers"`
+}
+
+type PostTierRequest struct {
+	Name       string `json:"name"`
+	PriceCents int    `json:"price_cents"`
+}
+type PostTierResponse struct {
+	Tier Tier `json:"tier"`
+}
+
+type GetResponse struct {
+	TierId         int32     `json:"tier_id"`
+	TierName       string    `json:"tier_name"`
+	PriceCents     int       `json:"price_cents"`
+	NextTierId     int32     `json:"next_tier_id,omitempty"`
+	SubscribedFrom time.Time `json:"subscribed_from"`
+	PeriodEnd      time.Time `json:"period_end"`
+	Months         int       `json:"months"`
+	AutoRenew      bool      `json:"auto_renew"`
+}
+
+type PostRequest struct {
+	TierId int32 `json:"tier_id"`
+}
+type PostResponse = GetResponse
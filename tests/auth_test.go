@@ -0,0 +1,259 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+	appAuth "twitchy-api/internal/app/auth"
+	"twitchy-api/internal/external/mailer"
+	"twitchy-api/internal/lib/password"
+	api "twitchy-api/pkg/api/auth"
+	userApi "twitchy-api/pkg/api/user"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthVerifyTestSuite struct {
+	suite.Suite
+	url  string
+	sink *mailer.Sink
+}
+
+func (s *AuthVerifyTestSuite) SetupSuite() {
+	s.url = ts.URL + "/api/auth"
+
+	sink, ok := app.Mailer.(*mailer.Sink)
+	s.Require().True(ok, "mailer is not a sink")
+	s.sink = sink
+}
+
+func TestAuthVerifySuite(t *testing.T) {
+	suite.Run(t, new(AuthVerifyTestSuite))
+}
+
+var verifyTokenRe = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func (s *AuthVerifyTestSuite) TestVerifyEmail() {
+	email := "verify-user@example.com"
+
+	resp := s.post("/signup", api.RegisterRequest{Email: email, Username: "verify-user", Password: "password123"})
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	msg, err := s.sink.Last(email)
+	s.Require().NoError(err)
+
+	match := verifyTokenRe.FindStringSubmatch(msg.Body)
+	s.Require().Len(match, 2)
+
+	resp = s.post("/verify", api.VerifyRequest{Token: match[1]})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	// token can be used only once
+	resp = s.post("/verify", api.VerifyRequest{Token: match[1]})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	// same email in other case
+	resp = s.post("/signup", api.RegisterRequest{Email: "Verify-User@example.com", Username: "verify-user-2", Password: "password123"})
+	s.Equal(http.StatusConflict, resp.StatusCode)
+}
+
+func (s *AuthVerifyTestSuite) TestResetPassword() {
+	email := "reset-user@example.com"
+	username := "reset-user"
+
+	resp := s.post("/signup", api.RegisterRequest{Email: email, Username: username, Password: "password123"})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.post("/verify", api.VerifyRequest{Token: s.lastToken(email)})
+
+	resp = s.post("/password/forgot", api.ForgotPasswordRequest{Username: username})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+	token := s.lastToken(email)
+
+	resp = s.post("/password/reset", api.ResetPasswordRequest{Token: token, Password: "short"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.post("/password/reset", api.ResetPasswordRequest{Token: token, Password: "new-password123"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	// token is single-use
+	resp = s.post("/password/reset", api.ResetPasswordRequest{Token: token, Password: "new-password456"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.post("/signin", api.LoginRequest{Username: username, Password: "password123"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.post("/signin", api.LoginRequest{Username: username, Password: "new-password123"})
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	// unknown users look the same
+	resp = s.post("/password/forgot", api.ForgotPasswordRequest{Username: "no-such-reset-user"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+func (s *AuthVerifyTestSuite) TestSignUpIssuesUserID() {
+	body, err := json.Marshal(api.RegisterRequest{Username: "signup-id-user", Password: "password123"})
+	s.Require().NoError(err)
+
+	resp, err := http.Post(s.url+"/signup", "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var res api.RegisterResponse
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&res))
+
+	claims := &appAuth.Claims{}
+	_, _, err = jwt.NewParser().ParseUnverified(res.Access, claims)
+	s.Require().NoError(err)
+	s.NotZero(claims.Id)
+
+	resp = s.post("/signup", api.RegisterRequest{Username: "signup-weak-user", Password: "short"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+// POST /users keeps its old request and response
+func (s *AuthVerifyTestSuite) TestCreateUser() {
+	resp := doRequest(&s.Suite, http.MethodPost, ts.URL+"/api/users", "", userApi.PostRequest{Name: "create-user", Password: "password123"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, ts.URL+"/api/users", "", api.RegisterRequest{Username: "create-user-2", Password: "password123"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	// both paths create users the same way
+	resp = s.post("/signup", api.RegisterRequest{Username: "create-user", Password: "password123"})
+	s.Equal(http.StatusConflict, resp.StatusCode)
+
+	resp = s.post("/signin", api.LoginRequest{Username: "create-user", Password: "password123"})
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *AuthVerifyTestSuite) TestSignUpInvalidEmail() {
+	resp := s.post("/signup", api.RegisterRequest{Email: "not an email", Username: "invalid-email-user", Password: "password123"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *AuthVerifyTestSuite) TestTokenTypes() {
+	resp := s.post("/signup", api.RegisterRequest{Username: "token-type-user", Password: "password123"})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var refresh *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "refreshToken" {
+			refresh = c
+		}
+	}
+	s.Require().NotNil(refresh)
+
+	access, _ := signUp(&s.Suite, ts.URL+"/api", "token-type-user-2")
+
+	// refresh tokens are not accepted in place of access tokens
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/signout", refresh.Value, nil)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	// and access tokens in place of refresh tokens
+	resp = s.refresh(access)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp = s.refresh(refresh.Value)
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *AuthVerifyTestSuite) TestRevocation() {
+	first, id := signUp(&s.Suite, ts.URL+"/api", "revoke-user")
+	user := fmt.Sprintf("%s/api/users/%d", ts.URL, id)
+	second := s.signIn("revoke-user")
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/signout", first, nil)
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	// only the signed out session is revoked
+	resp = doRequest(&s.Suite, http.MethodGet, user, first, nil)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodGet, user, second, nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/signout-all", second, nil)
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodGet, user, second, nil)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	// iat has whole second precision, so tokens issued within the second of signing out
+	// everywhere are denied as well. tokens of the next second are accepted
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	third := s.signIn("revoke-user")
+
+	resp = doRequest(&s.Suite, http.MethodGet, user, third, nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+// passwords stored before hashing are replaced with a hash on sign in
+func (s *AuthVerifyTestSuite) TestLegacyHashUpgraded() {
+	signUp(&s.Suite, ts.URL+"/api", "legacy-hash-user")
+
+	ctx := context.Background()
+	_, err := pgpool.Exec(ctx, `
+		UPDATE tc_user SET password = 'password123', password_algo = 'plain'
+		WHERE name = 'legacy-hash-user'`)
+	s.Require().NoError(err)
+
+	s.signIn("legacy-hash-user")
+
+	var hash, algo string
+	err = pgpool.QueryRow(ctx, `
+		SELECT password, password_algo::text FROM tc_user
+		WHERE name = 'legacy-hash-user'`).Scan(&hash, &algo)
+	s.Require().NoError(err)
+	s.Equal(password.AlgoBcrypt, algo)
+	s.NotEqual("password123", hash)
+
+	// and the new hash works
+	s.signIn("legacy-hash-user")
+
+	resp := s.post("/signin", api.LoginRequest{Username: "legacy-hash-user", Password: "wrong-password"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *AuthVerifyTestSuite) signIn(username string) string {
+	return signIn(&s.Suite, ts.URL+"/api", username)
+}
+
+func (s *AuthVerifyTestSuite) refresh(token string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, s.url+"/refresh", nil)
+	s.Require().NoError(err)
+	req.AddCookie(&http.Cookie{Name: "refreshToken", Value: token})
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	return resp
+}
+
+func (s *AuthVerifyTestSuite) lastToken(email string) string {
+	msg, err := s.sink.Last(email)
+	s.Require().NoError(err)
+
+	match := verifyTokenRe.FindStringSubmatch(msg.Body)
+	s.Require().Len(match, 2)
+
+	return match[1]
+}
+
+func (s *AuthVerifyTestSuite) post(path string, body any) *http.Response {
+	b, err := json.Marshal(body)
+	s.Require().NoError(err)
+
+	resp, err := http.Post(s.url+path, "application/json", bytes.NewReader(b))
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	return resp
+}
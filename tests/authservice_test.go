@@ -0,0 +1,217 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	application "twitchy-api/internal/app"
+	appAuth "twitchy-api/internal/app/auth"
+	"twitchy-api/internal/app/authz"
+	authHandler "twitchy-api/internal/auth"
+	authStorage "twitchy-api/internal/auth/storage"
+	extAuth "twitchy-api/internal/external/auth"
+	auth_v1 "twitchy-api/internal/lib/gen/auth"
+	api "twitchy-api/pkg/api/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+)
+
+// issues tokens the way the auth service does: only username and role come with the request,
+// so the tokens have neither id nor typ
+type authServiceFake struct {
+	auth_v1.UnimplementedAuthServer
+	keys *appAuth.Keys
+}
+
+func (f *authServiceFake) NewAccess(ctx context.Context, req *auth_v1.NewAccessRequest) (*auth_v1.NewAccessResponse, error) {
+	token, err := f.sign(req.Username, req.Role, 2*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_v1.NewAccessResponse{Token: token}, nil
+}
+
+func (f *authServiceFake) NewRefresh(ctx context.Context, req *auth_v1.NewRefreshRequest) (*auth_v1.NewRefreshResponse, error) {
+	token, err := f.sign(req.Username, req.Role, 240*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_v1.NewRefreshResponse{Token: token}, nil
+}
+
+func (f *authServiceFake) Blacklist(ctx context.Context, req *auth_v1.BlacklistRequest) (*auth_v1.BlacklistResponse, error) {
+	return &auth_v1.BlacklistResponse{}, nil
+}
+
+func (f *authServiceFake) sign(username, role string, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	return f.keys.Sign(&appAuth.Claims{
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+}
+
+// auth endpoints backed by ClientImpl talking to authServiceFake over grpc
+type AuthServiceTestSuite struct {
+	suite.Suite
+	grpc *grpc.Server
+	ts   *httptest.Server
+}
+
+func TestAuthServiceSuite(t *testing.T) {
+	suite.Run(t, new(AuthServiceTestSuite))
+}
+
+func (s *AuthServiceTestSuite) SetupSuite() {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+
+	s.grpc = grpc.NewServer()
+	auth_v1.RegisterAuthServer(s.grpc, &authServiceFake{keys: app.Keys})
+	go s.grpc.Serve(lis) // nolint:errcheck
+
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	s.Require().NoError(err)
+
+	client, err := extAuth.NewClient(logger, host, port, 5*time.Second, 1)
+	s.Require().NoError(err)
+
+	h := authHandler.NewHandler(logger, authStorage.NewService(logger,
+		client,
+		pgpool,
+		rclient,
+		app.Keys,
+		app.Mailer,
+		authStorage.MailLinks{Verify: "http://localhost/verify?token={token}", Reset: "http://localhost/reset?token={token}"}))
+
+	authMw := application.NewAuthMiddleware(logger, false, rclient, app.Keys, app.UserRepo)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/signup", h.SignUp)
+	mux.HandleFunc("POST /auth/refresh", h.Refresh)
+	mux.HandleFunc("POST /auth/verify/resend", authMw(h.ResendVerification))
+	mux.HandleFunc("PATCH /users/{id}", authMw(authz.New(logger, nil).Require(authz.UsersEdit, authz.PathUser("id"))(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	mux.HandleFunc("GET /claims", authMw(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := appAuth.FromContext(r.Context())
+		json.NewEncoder(w).Encode(claims) // nolint:errcheck
+	}))
+
+	s.ts = httptest.NewServer(mux)
+}
+
+func (s *AuthServiceTestSuite) TearDownSuite() {
+	s.ts.Close()
+	s.grpc.Stop()
+}
+
+// tokens without typ are accepted as access tokens and can be refreshed
+func (s *AuthServiceTestSuite) TestTokensWithoutType() {
+	body, err := json.Marshal(api.RegisterRequest{Username: "authservice-user", Password: "password123"})
+	s.Require().NoError(err)
+
+	resp, err := http.Post(s.ts.URL+"/auth/signup", "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var res api.RegisterResponse
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&res))
+
+	var claims appAuth.Claims
+	getJSON(&s.Suite, s.ts.URL+"/claims", res.Access, &claims)
+	s.Equal("authservice-user", claims.Username)
+	s.Empty(claims.Type)
+
+	var refresh *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "refreshToken" {
+			refresh = c
+		}
+	}
+	s.Require().NotNil(refresh)
+
+	req, err := http.NewRequest(http.MethodPost, s.ts.URL+"/auth/refresh", nil)
+	s.Require().NoError(err)
+	req.AddCookie(refresh)
+
+	refreshResp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	defer refreshResp.Body.Close()
+	s.Require().Equal(http.StatusOK, refreshResp.StatusCode)
+
+	var refreshed api.RefreshResponse
+	s.Require().NoError(json.NewDecoder(refreshResp.Body).Decode(&refreshed))
+
+	getJSON(&s.Suite, s.ts.URL+"/claims", refreshed.Access, &claims)
+	s.Equal("authservice-user", claims.Username)
+
+	// access tokens are not in any refresh family
+	req, err = http.NewRequest(http.MethodPost, s.ts.URL+"/auth/refresh", nil)
+	s.Require().NoError(err)
+	req.AddCookie(&http.Cookie{Name: "refreshToken", Value: res.Access})
+
+	accessResp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	accessResp.Body.Close()
+	s.Equal(http.StatusUnauthorized, accessResp.StatusCode)
+}
+
+// ids missing from the tokens are resolved by username before handlers see them
+func (s *AuthServiceTestSuite) TestTokensWithoutId() {
+	body, err := json.Marshal(api.RegisterRequest{
+		Email:    "authservice-id-user@example.com",
+		Username: "authservice-id-user",
+		Password: "password123"})
+	s.Require().NoError(err)
+
+	resp, err := http.Post(s.ts.URL+"/auth/signup", "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var res api.RegisterResponse
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&res))
+
+	issued := &appAuth.Claims{}
+	_, _, err = jwt.NewParser().ParseUnverified(res.Access, issued)
+	s.Require().NoError(err)
+	s.Zero(issued.Id)
+
+	var id int32
+	err = pgpool.QueryRow(context.Background(), "SELECT id FROM tc_user WHERE name = 'authservice-id-user'").Scan(&id)
+	s.Require().NoError(err)
+
+	var claims appAuth.Claims
+	getJSON(&s.Suite, s.ts.URL+"/claims", res.Access, &claims)
+	s.Equal(id, claims.Id)
+
+	resp = doRequest(&s.Suite, http.MethodPatch, fmt.Sprintf("%s/users/%d", s.ts.URL, id), res.Access, nil)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	_, otherId := signUp(&s.Suite, ts.URL+"/api", "authservice-other-user")
+	resp = doRequest(&s.Suite, http.MethodPatch, fmt.Sprintf("%s/users/%d", s.ts.URL, otherId), res.Access, nil)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.ts.URL+"/auth/verify/resend", res.Access, nil)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
@@ -0,0 +1,342 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	application "twitchy-api/internal/app"
+	appAuth "twitchy-api/internal/app/auth"
+	api "twitchy-api/pkg/api/auth"
+	channelApi "twitchy-api/pkg/api/channel"
+	followApi "twitchy-api/pkg/api/follow"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthzTestSuite struct {
+	suite.Suite
+	url string
+}
+
+func (s *AuthzTestSuite) SetupSuite() {
+	s.url = ts.URL + "/api"
+}
+
+func TestAuthzSuite(t *testing.T) {
+	suite.Run(t, new(AuthzTestSuite))
+}
+
+func (s *AuthzTestSuite) TestUserCantEditOthers() {
+	token, _ := s.signUp("authz-user")
+	_, otherId := s.signUp("authz-other-user")
+
+	resp := s.do(http.MethodPatch, fmt.Sprintf("/users/%d", otherId), token, map[string]any{"pfp": "pfp.png"})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = s.do(http.MethodDelete, fmt.Sprintf("/users/%d", otherId), token, map[string]any{"id": otherId})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = s.do(http.MethodPatch, fmt.Sprintf("/users/%d", otherId), "", map[string]any{"pfp": "pfp.png"})
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (s *AuthzTestSuite) TestStaffCanDeleteUsers() {
+	s.signUp("authz-staff")
+	_, otherId := s.signUp("authz-deleted-user")
+
+	_, err := pgpool.Exec(context.Background(), `UPDATE tc_user SET app_role = 'staff' WHERE name = 'authz-staff'`)
+	s.Require().NoError(err)
+
+	// role is read on sign in
+	token := signIn(&s.Suite, s.url, "authz-staff")
+
+	resp := s.do(http.MethodDelete, fmt.Sprintf("/users/%d", otherId), token, map[string]any{"id": otherId})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+func (s *AuthzTestSuite) TestUserCantSetOwnFlags() {
+	token, id := s.signUp("authz-flags-user")
+
+	resp := s.do(http.MethodPatch, fmt.Sprintf("/users/%d", id), token, map[string]any{"is_partner": true})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = s.do(http.MethodPatch, fmt.Sprintf("/users/%d", id), token, map[string]any{"pfp": "pfp.png"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+func (s *AuthzTestSuite) TestUserCantManageCategories() {
+	token, _ := s.signUp("authz-category-user")
+
+	resp := s.do(http.MethodPost, "/categories", token, map[string]any{"name": "Forbidden", "link": "forbidden"})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = s.do(http.MethodGet, "/users", token, nil)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (s *AuthzTestSuite) TestChannelModerators() {
+	ownerToken, _ := s.signUp("authz-streamer")
+	modToken, modId := s.signUp("authz-moderator")
+	s.signUp("authz-viewer")
+
+	resp := s.do(http.MethodPost, "/channels/authz-streamer/moderators", ownerToken, channelApi.AddModeratorRequest{Username: "authz-moderator"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	// appointing twice is a no-op
+	resp = s.do(http.MethodPost, "/channels/authz-streamer/moderators", ownerToken, channelApi.AddModeratorRequest{Username: "authz-moderator"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/channels/authz-streamer/moderators", ownerToken, channelApi.AddModeratorRequest{Username: "authz-streamer"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/channels/authz-streamer/moderators", ownerToken, channelApi.AddModeratorRequest{Username: "authz-nobody"})
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// moderators can't appoint other moderators
+	resp = s.do(http.MethodPost, "/channels/authz-streamer/moderators", modToken, channelApi.AddModeratorRequest{Username: "authz-viewer"})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	listResp, err := http.Get(s.url + "/channels/authz-streamer/moderators")
+	s.Require().NoError(err)
+	defer listResp.Body.Close()
+	s.Require().Equal(http.StatusOK, listResp.StatusCode)
+
+	var list channelApi.ListModeratorsResponse
+	s.Require().NoError(json.NewDecoder(listResp.Body).Decode(&list))
+	s.Require().Len(list.Moderators, 1)
+	s.Equal(modId, list.Moderators[0].Id)
+
+	mod, err := app.ChannelRepo.IsModerator(context.Background(), "authz-streamer", modId)
+	s.NoError(err)
+	s.True(mod)
+
+	resp = s.do(http.MethodDelete, "/channels/authz-streamer/moderators/authz-moderator", ownerToken, nil)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	mod, err = app.ChannelRepo.IsModerator(context.Background(), "authz-streamer", modId)
+	s.NoError(err)
+	s.False(mod)
+}
+
+func (s *AuthzTestSuite) TestChannelBans() {
+	ownerToken, _ := s.signUp("ban-streamer")
+	modToken, _ := s.signUp("ban-moderator")
+	viewerToken, _ := s.signUp("ban-viewer")
+
+	resp := s.do(http.MethodPost, "/channels/ban-streamer/moderators", ownerToken, channelApi.AddModeratorRequest{Username: "ban-moderator"})
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	// viewers can't ban
+	resp = s.do(http.MethodPost, "/channels/ban-streamer/bans", viewerToken, channelApi.BanRequest{Username: "ban-moderator"})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/channels/ban-streamer/bans", modToken, channelApi.BanRequest{Username: "ban-streamer"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/channels/ban-streamer/bans", modToken, channelApi.BanRequest{Username: "ban-viewer", DurationSeconds: -1})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/channels/ban-streamer/bans", modToken, channelApi.BanRequest{Username: "ban-viewer", DurationSeconds: 600, Reason: "spam"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/follow/ban-viewer", viewerToken, followApi.PostRequest{Follow: "ban-streamer"})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, s.url+"/channels/ban-streamer/bans", nil)
+	s.Require().NoError(err)
+	req.Header.Set("Authorization", "Bearer "+modToken)
+
+	listResp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	defer listResp.Body.Close()
+	s.Require().Equal(http.StatusOK, listResp.StatusCode)
+
+	var list channelApi.ListBansResponse
+	s.Require().NoError(json.NewDecoder(listResp.Body).Decode(&list))
+	s.Require().Len(list.Bans, 1)
+	s.Equal("ban-viewer", list.Bans[0].Name)
+	s.Equal("ban-moderator", list.Bans[0].BannedBy)
+	s.NotNil(list.Bans[0].ExpiresAt)
+
+	resp = s.do(http.MethodDelete, "/channels/ban-streamer/bans/ban-viewer", modToken, nil)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/follow/ban-viewer", viewerToken, followApi.PostRequest{Follow: "ban-streamer"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+// moderators can't ban each other, the owner can ban any of them
+func (s *AuthzTestSuite) TestModeratorBans() {
+	ownerToken, _ := s.signUp("modban-streamer")
+	modToken, _ := s.signUp("modban-moderator")
+	s.signUp("modban-other")
+
+	for _, name := range []string{"modban-moderator", "modban-other"} {
+		resp := s.do(http.MethodPost, "/channels/modban-streamer/moderators", ownerToken, channelApi.AddModeratorRequest{Username: name})
+		s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+	}
+
+	resp := s.do(http.MethodPost, "/channels/modban-streamer/bans", modToken, channelApi.BanRequest{Username: "modban-other"})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/channels/modban-streamer/bans", ownerToken, channelApi.BanRequest{Username: "modban-other"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+// banning a follower removes the follow and the cached follower count drops with it
+func (s *AuthzTestSuite) TestBanRemovesFollow() {
+	ownerToken, _ := s.signUp("banfollow-streamer")
+	viewerToken, _ := s.signUp("banfollow-viewer")
+
+	resp := s.do(http.MethodPost, "/follow/banfollow-viewer", viewerToken, followApi.PostRequest{Follow: "banfollow-streamer"})
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	var ch channelApi.GetResponse
+	getJSON(&s.Suite, s.url+"/channels/banfollow-streamer", "", &ch)
+	s.Require().Equal(int64(1), ch.Followers)
+
+	resp = s.do(http.MethodPost, "/channels/banfollow-streamer/bans", ownerToken, channelApi.BanRequest{Username: "banfollow-viewer"})
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	getJSON(&s.Suite, s.url+"/channels/banfollow-streamer", "", &ch)
+	s.Equal(int64(0), ch.Followers)
+
+	var follows int
+	err := pgpool.QueryRow(context.Background(), `
+		SELECT count(*) FROM tc_user_follow f
+		JOIN tc_user u ON u.id = f.id_follow
+		WHERE u.name = 'banfollow-streamer'`).Scan(&follows)
+	s.Require().NoError(err)
+	s.Equal(0, follows)
+}
+
+// expired bans stop applying right away and are deleted by the cleanup task
+func (s *AuthzTestSuite) TestBanExpiry() {
+	ownerToken, _ := s.signUp("banexpiry-streamer")
+	viewerToken, _ := s.signUp("banexpiry-viewer")
+
+	resp := s.do(http.MethodPost, "/channels/banexpiry-streamer/bans", ownerToken, channelApi.BanRequest{Username: "banexpiry-viewer", DurationSeconds: 600})
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = s.do(http.MethodPost, "/follow/banexpiry-viewer", viewerToken, followApi.PostRequest{Follow: "banexpiry-streamer"})
+	s.Require().Equal(http.StatusForbidden, resp.StatusCode)
+
+	ctx := context.Background()
+	_, err := pgpool.Exec(ctx, `
+		UPDATE tc_user_banned SET expires_at = now() - interval '1 minute'
+		WHERE id_channel = (SELECT id FROM tc_user WHERE name = 'banexpiry-streamer')`)
+	s.Require().NoError(err)
+
+	resp = s.do(http.MethodPost, "/follow/banexpiry-viewer", viewerToken, followApi.PostRequest{Follow: "banexpiry-streamer"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	s.Require().NoError(app.BanCleaner.HandleCleanupTask(ctx, nil))
+
+	var bans int
+	err = pgpool.QueryRow(ctx, `
+		SELECT count(*) FROM tc_user_banned
+		WHERE id_channel = (SELECT id FROM tc_user WHERE name = 'banexpiry-streamer')`).Scan(&bans)
+	s.Require().NoError(err)
+	s.Equal(0, bans)
+}
+
+// metrics are only served by the internal listener
+func (s *AuthzTestSuite) TestDebugVarsNotPublic() {
+	resp := doRequest(&s.Suite, http.MethodGet, ts.URL+"/debug/vars", "", nil)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	internal := httptest.NewServer(application.CreateInternalHandler())
+	defer internal.Close()
+
+	resp = doRequest(&s.Suite, http.MethodGet, internal.URL+"/debug/vars", "", nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *AuthzTestSuite) signUp(username string) (string, int32) {
+	return signUp(&s.Suite, s.url, username)
+}
+
+func (s *AuthzTestSuite) do(method, path, token string, body any) *http.Response {
+	return doRequest(&s.Suite, method, s.url+path, token, body)
+}
+
+// signs up a user and returns its access token and id
+func signUp(s *suite.Suite, url, username string) (string, int32) {
+	body, err := json.Marshal(api.RegisterRequest{Username: username, Password: "password123"})
+	s.Require().NoError(err)
+
+	resp, err := http.Post(url+"/auth/signup", "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var res api.RegisterResponse
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&res))
+
+	claims := &appAuth.Claims{}
+	_, _, err = jwt.NewParser().ParseUnverified(res.Access, claims)
+	s.Require().NoError(err)
+
+	return res.Access, claims.Id
+}
+
+// signs in a user created by signUp and returns its access token
+func signIn(s *suite.Suite, url, username string) string {
+	body, err := json.Marshal(api.LoginRequest{Username: username, Password: "password123"})
+	s.Require().NoError(err)
+
+	resp, err := http.Post(url+"/auth/signin", "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var res api.LoginResponse
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&res))
+
+	return res.Access
+}
+
+// sends json body with bearer token, both are optional. the response body is closed
+func doRequest(s *suite.Suite, method, url, token string, body any) *http.Response {
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		s.Require().NoError(err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(b))
+	s.Require().NoError(err)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	return resp
+}
+
+// gets url with optional bearer token, expects 200 and decodes the json response into v
+func getJSON(s *suite.Suite, url, token string, v any) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	s.Require().NoError(err)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(v))
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	categoryDomain "twitchy-api/internal/category/domain"
+	livestreamDomain "twitchy-api/internal/livestream/domain"
+	channelAPI "twitchy-api/pkg/api/channel"
+	api "twitchy-api/pkg/api/follow"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FollowTestSuite struct {
+	suite.Suite
+	url string
+}
+
+func (s *FollowTestSuite) SetupSuite() {
+	s.url = ts.URL + "/api"
+}
+
+func TestFollowSuite(t *testing.T) {
+	suite.Run(t, new(FollowTestSuite))
+}
+
+func (s *FollowTestSuite) TestFollowers() {
+	signUp(&s.Suite, s.url, "followers-streamer")
+
+	// reading the count first caches it, follows below must keep it in sync
+	s.Equal(int64(0), s.followers("followers-streamer"))
+
+	var names []string
+	for i := range 3 {
+		name := fmt.Sprintf("followers-viewer-%d", i)
+		token, _ := signUp(&s.Suite, s.url, name)
+		s.follow(name, token, "followers-streamer")
+		names = append(names, name)
+	}
+
+	// following twice is not counted twice
+	token, _ := signUp(&s.Suite, s.url, "followers-viewer-again")
+	s.follow("followers-viewer-again", token, "followers-streamer")
+	s.follow("followers-viewer-again", token, "followers-streamer")
+	names = append(names, "followers-viewer-again")
+
+	s.Equal(int64(4), s.followers("followers-streamer"))
+
+	var first api.FollowersResponse
+	getJSON(&s.Suite, s.url+"/channels/followers-streamer/followers?count=3", "", &first)
+	s.Require().Len(first.Followers, 3)
+	s.Require().NotEmpty(first.NextCursor)
+
+	var second api.FollowersResponse
+	getJSON(&s.Suite, s.url+"/channels/followers-streamer/followers?count=3&cursor="+url.QueryEscape(first.NextCursor), "", &second)
+	s.Require().Len(second.Followers, 1)
+	s.Empty(second.NextCursor)
+
+	// most recent first, no follower is repeated or skipped
+	var got []string
+	for _, f := range append(first.Followers, second.Followers...) {
+		s.False(f.FollowingFrom.IsZero())
+		got = append([]string{f.Name}, got...)
+	}
+	s.Equal(names, got)
+
+	resp := doRequest(&s.Suite, http.MethodDelete, s.url+"/follow/followers-viewer-again", token, api.DeleteRequest{Unfollow: "followers-streamer"})
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+	s.Equal(int64(3), s.followers("followers-streamer"))
+
+	resp = doRequest(&s.Suite, http.MethodGet, s.url+"/channels/followers-streamer/followers?cursor=not-a-cursor", "", nil)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodGet, s.url+"/channels/this_user_should_not_exist/followers", "", nil)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *FollowTestSuite) TestFollowErrors() {
+	token, _ := signUp(&s.Suite, s.url, "follow-errors-viewer")
+	signUp(&s.Suite, s.url, "follow-errors-streamer")
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/follow/follow-errors-viewer", token, api.PostRequest{Follow: "this_user_should_not_exist"})
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/follow/follow-errors-viewer", token, api.PostRequest{Follow: "follow-errors-viewer"})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodDelete, s.url+"/follow/follow-errors-viewer", token, api.DeleteRequest{Unfollow: "this_user_should_not_exist"})
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// following and unfollowing are idempotent
+	for range 2 {
+		resp = doRequest(&s.Suite, http.MethodPost, s.url+"/follow/follow-errors-viewer", token, api.PostRequest{Follow: "follow-errors-streamer"})
+		s.Equal(http.StatusNoContent, resp.StatusCode)
+	}
+	s.Equal(int64(1), s.followers("follow-errors-streamer"))
+
+	for range 2 {
+		resp = doRequest(&s.Suite, http.MethodDelete, s.url+"/follow/follow-errors-viewer", token, api.DeleteRequest{Unfollow: "follow-errors-streamer"})
+		s.Equal(http.StatusNoContent, resp.StatusCode)
+	}
+	s.Equal(int64(0), s.followers("follow-errors-streamer"))
+}
+
+func (s *FollowTestSuite) TestFollowingExtended() {
+	ctx := context.Background()
+
+	link := "following-extended-category"
+	err := app.CategoryRepo.Create(ctx, categoryDomain.CategoryCreate{Name: link, Link: link, Tags: []int{}})
+	s.Require().NoError(err)
+
+	cat, err := app.CategoryRepo.GetByLink(ctx, link)
+	s.Require().NoError(err)
+
+	token, _ := signUp(&s.Suite, s.url, "following-extended-viewer")
+	_, liveId := signUp(&s.Suite, s.url, "following-extended-live")
+	signUp(&s.Suite, s.url, "following-extended-offline")
+
+	s.follow("following-extended-viewer", token, "following-extended-offline")
+	s.follow("following-extended-viewer", token, "following-extended-live")
+
+	_, err = pgpool.Exec(ctx, "UPDATE tc_user SET id_category = $1 WHERE id = $2", cat.Id, liveId)
+	s.Require().NoError(err)
+
+	ls, err := app.LivestreamRepo.Create(ctx, livestreamDomain.LivestreamCreate{Username: "following-extended-live"})
+	s.Require().NoError(err)
+	defer app.LivestreamRepo.Delete(ctx, ls.Id) // nolint
+
+	// live status comes from the livestream cache, a stale is_live column doesn't matter
+	_, err = pgpool.Exec(ctx, `UPDATE tc_user SET is_live = (name = 'following-extended-offline')
+		WHERE name IN ('following-extended-live', 'following-extended-offline')`)
+	s.Require().NoError(err)
+
+	path := "/follow?follower=following-extended-viewer&extended=true"
+
+	var all api.ListExtendedResponse
+	getJSON(&s.Suite, s.url+path, "", &all)
+	s.Require().Len(all.FollowList, 2)
+	s.Equal("following-extended-live", all.FollowList[0].Name)
+	s.True(all.FollowList[0].IsLive)
+	s.Equal(link, all.FollowList[0].Category)
+	s.Equal("following-extended-offline", all.FollowList[1].Name)
+	s.False(all.FollowList[1].IsLive)
+
+	var live api.ListExtendedResponse
+	getJSON(&s.Suite, s.url+path+"&live=true", "", &live)
+	s.Require().Len(live.FollowList, 1)
+	s.Equal("following-extended-live", live.FollowList[0].Name)
+
+	var offline api.ListExtendedResponse
+	getJSON(&s.Suite, s.url+path+"&live=false", "", &offline)
+	s.Require().Len(offline.FollowList, 1)
+	s.Equal("following-extended-offline", offline.FollowList[0].Name)
+
+	var page api.ListExtendedResponse
+	getJSON(&s.Suite, s.url+path+"&count=1&page=1", "", &page)
+	s.Require().Len(page.FollowList, 1)
+	s.Equal("following-extended-live", page.FollowList[0].Name)
+
+	getJSON(&s.Suite, s.url+path+"&count=1&page=2", "", &page)
+	s.Require().Len(page.FollowList, 1)
+	s.Equal("following-extended-offline", page.FollowList[0].Name)
+
+	getJSON(&s.Suite, s.url+path+"&count=1&page=3", "", &page)
+	s.Empty(page.FollowList)
+
+	// count above the limit is clamped
+	getJSON(&s.Suite, s.url+path+"&count=100000", "", &page)
+	s.Len(page.FollowList, 2)
+
+	resp := doRequest(&s.Suite, http.MethodGet, s.url+path+"&live=maybe", "", nil)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *FollowTestSuite) follow(username, token, channel string) {
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/follow/"+username, token, api.PostRequest{Follow: channel})
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+func (s *FollowTestSuite) followers(channel string) int64 {
+	var res channelAPI.GetResponse
+	getJSON(&s.Suite, s.url+"/channels/"+channel, "", &res)
+	return res.Followers
+}
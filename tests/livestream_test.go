@@ -1,12 +1,22 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
+	categoryDomain "twitchy-api/internal/category/domain"
+	"twitchy-api/internal/external/streamserver"
+	livestreamDomain "twitchy-api/internal/livestream/domain"
+	livestreamService "twitchy-api/internal/livestream/service"
+	livestreamStorage "twitchy-api/internal/livestream/storage"
+	followAPI "twitchy-api/pkg/api/follow"
 	livestreamAPI "twitchy-api/pkg/api/livestream"
+	subscriptionAPI "twitchy-api/pkg/api/subscription"
 
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -46,3 +56,303 @@ func (s *LivestreamHandlerTestSuite) TestGetLivestream() {
 	}
 	s.Equal(livestreamID, res.Id)
 }
+
+// records scheduled update tasks instead of registering them in asynq
+type schedulerMock struct {
+	// task id (livestream id) -> entry id
+	tasks map[string]string
+	calls int
+}
+
+func (m *schedulerMock) Schedule(every time.Duration, taskType string, payload []byte, taskId string) (string, error) {
+	m.calls += 1
+	entryId := fmt.Sprintf("entry-%d", m.calls)
+	m.tasks[taskId] = entryId
+	return entryId, nil
+}
+
+func (m *schedulerMock) Unregister(entryId string) error {
+	for taskId, id := range m.tasks {
+		if id == entryId {
+			delete(m.tasks, taskId)
+		}
+	}
+	return nil
+}
+
+type LivestreamResumeTestSuite struct {
+	suite.Suite
+	// livestream id -> category link
+	livestreams map[int]string
+}
+
+func (s *LivestreamResumeTestSuite) SetupSuite() {
+	ctx := context.Background()
+	s.livestreams = make(map[int]string)
+
+	links := []string{"resume-category-1", "resume-category-2", "resume-category-3"}
+	for i, link := range links {
+		err := app.CategoryRepo.Create(ctx, categoryDomain.CategoryCreate{
+			Name: link,
+			Link: link,
+			Tags: []int{},
+		})
+		s.Require().NoError(err)
+
+		cat, err := app.CategoryRepo.GetByLink(ctx, link)
+		s.Require().NoError(err)
+
+		for j := range 2 {
+			username := fmt.Sprintf("resume_user_%d_%d", i, j)
+			_, err := pgpool.Exec(ctx,
+				"INSERT INTO tc_user(name, password, id_category) VALUES ($1, 'password', $2)",
+				username, cat.Id)
+			s.Require().NoError(err)
+
+			ls, err := app.LivestreamRepo.Create(ctx, livestreamDomain.LivestreamCreate{Username: username})
+			s.Require().NoError(err)
+			s.Require().Equal(link, ls.CategoryLink)
+
+			s.livestreams[ls.Id] = link
+		}
+	}
+}
+
+func TestLivestreamResumeSuite(t *testing.T) {
+	suite.Run(t, new(LivestreamResumeTestSuite))
+}
+
+func (s *LivestreamResumeTestSuite) TestResumeAllCategories() {
+	ctx := context.Background()
+
+	sched := &schedulerMock{tasks: make(map[string]string)}
+	u := livestreamService.NewUpdater(logger,
+		rclient,
+		app.StreamServerAdapter,
+		app.LivestreamRepo,
+		sched,
+		"resume-test-instance")
+	s.Require().NotNil(u)
+
+	err := u.Resume(ctx)
+	s.Require().NoError(err)
+
+	categories := make(map[string]struct{})
+	for id, link := range s.livestreams {
+		s.Contains(sched.tasks, strconv.Itoa(id), "livestream %d in %s was not resumed", id, link)
+		categories[link] = struct{}{}
+	}
+	s.Len(categories, 3)
+
+	// resuming again must not register duplicate tasks
+	calls := sched.calls
+	err = u.Resume(ctx)
+	s.Require().NoError(err)
+	s.Equal(calls, sched.calls)
+
+	for id := range s.livestreams {
+		rclient.Del(ctx, fmt.Sprintf("livestream_update_tasks:%d", id))
+	}
+}
+
+type LivestreamPersonalizeTestSuite struct {
+	suite.Suite
+	url string
+}
+
+func (s *LivestreamPersonalizeTestSuite) SetupSuite() {
+	s.url = ts.URL + "/api"
+}
+
+func TestLivestreamPersonalizeSuite(t *testing.T) {
+	suite.Run(t, new(LivestreamPersonalizeTestSuite))
+}
+
+func (s *LivestreamPersonalizeTestSuite) TestFollowingAndSubscriber() {
+	ctx := context.Background()
+
+	link := "personalize-category"
+	err := app.CategoryRepo.Create(ctx, categoryDomain.CategoryCreate{Name: link, Link: link, Tags: []int{}})
+	s.Require().NoError(err)
+
+	cat, err := app.CategoryRepo.GetByLink(ctx, link)
+	s.Require().NoError(err)
+
+	ownerToken, ownerId := signUp(&s.Suite, s.url, "personalize-streamer")
+	viewerToken, _ := signUp(&s.Suite, s.url, "personalize-viewer")
+
+	_, err = pgpool.Exec(ctx, "UPDATE tc_user SET id_category = $1 WHERE id = $2", cat.Id, ownerId)
+	s.Require().NoError(err)
+
+	ls, err := app.LivestreamRepo.Create(ctx, livestreamDomain.LivestreamCreate{Username: "personalize-streamer"})
+	s.Require().NoError(err)
+	defer app.LivestreamRepo.Delete(ctx, ls.Id) // nolint
+
+	res := s.get(fmt.Sprintf("/livestreams/%d", ls.Id), viewerToken)
+	s.False(res.IsFollowing)
+	s.False(res.IsSubscriber)
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/follow/personalize-viewer", viewerToken, followAPI.PostRequest{Follow: "personalize-streamer"})
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/personalize-streamer/subscription/tiers", ownerToken, subscriptionAPI.PostTierRequest{Name: "Tier 1", PriceCents: 499})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	tiers, err := app.SubscriptionService.Tiers(ctx, "personalize-streamer")
+	s.Require().NoError(err)
+	s.Require().Len(tiers, 1)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/personalize-streamer/subscription", viewerToken, subscriptionAPI.PostRequest{TierId: tiers[0].Id})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	res = s.get(fmt.Sprintf("/livestreams/%d", ls.Id), viewerToken)
+	s.True(res.IsFollowing)
+	s.True(res.IsSubscriber)
+
+	res = s.get("/users/personalize-streamer/livestream", viewerToken)
+	s.True(res.IsFollowing)
+	s.True(res.IsSubscriber)
+
+	var list livestreamAPI.ListResponse
+	getJSON(&s.Suite, s.url+"/livestreams?count=100", viewerToken, &list)
+	found := false
+	for _, item := range list.Livestreams {
+		if item.Id == ls.Id {
+			found = true
+			s.True(item.IsFollowing)
+			s.True(item.IsSubscriber)
+		}
+	}
+	s.True(found, "livestream %d is not listed", ls.Id)
+
+	// anonymous and invalid tokens are served without personalization
+	res = s.get(fmt.Sprintf("/livestreams/%d", ls.Id), "")
+	s.False(res.IsFollowing)
+	s.False(res.IsSubscriber)
+
+	res = s.get(fmt.Sprintf("/livestreams/%d", ls.Id), "not-a-token")
+	s.False(res.IsFollowing)
+	s.False(res.IsSubscriber)
+}
+
+func (s *LivestreamPersonalizeTestSuite) get(path, token string) livestreamAPI.GetResponse {
+	var res livestreamAPI.GetResponse
+	getJSON(&s.Suite, s.url+path, token, &res)
+	return res
+}
+
+type LivestreamWebhookTestSuite struct {
+	suite.Suite
+	url string
+}
+
+func (s *LivestreamWebhookTestSuite) SetupSuite() {
+	s.url = ts.URL + "/webhooks/livestreams"
+}
+
+func TestLivestreamWebhookSuite(t *testing.T) {
+	suite.Run(t, new(LivestreamWebhookTestSuite))
+}
+
+func (s *LivestreamWebhookTestSuite) TestSecret() {
+	// ignored action, only the secret is checked
+	event := streamserver.StreamEventPayload{Action: "on_connect", Stream: "webhook-streamer"}
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url, "", event)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"?secret=wrong", "", event)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"?secret="+webhookSecret, "", event)
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+type BroadcastsTestSuite struct {
+	suite.Suite
+}
+
+func TestBroadcastsSuite(t *testing.T) {
+	suite.Run(t, new(BroadcastsTestSuite))
+}
+
+func (s *BroadcastsTestSuite) TestCount() {
+	ctx := context.Background()
+
+	link := "broadcasts-category"
+	err := app.CategoryRepo.Create(ctx, categoryDomain.CategoryCreate{Name: link, Link: link, Tags: []int{}})
+	s.Require().NoError(err)
+
+	cat, err := app.CategoryRepo.GetByLink(ctx, link)
+	s.Require().NoError(err)
+
+	_, id := signUp(&s.Suite, ts.URL+"/api", "broadcasts-streamer")
+
+	_, err = pgpool.Exec(ctx, `
+		INSERT INTO tc_livestream_history (id_user, id_category, started_at)
+		SELECT $1, $2, now() - make_interval(hours => n)
+		FROM generate_series(1, 101) AS n`, id, cat.Id)
+	s.Require().NoError(err)
+
+	path := ts.URL + "/api/channels/broadcasts-streamer/broadcasts"
+
+	var res livestreamAPI.BroadcastListResponse
+	getJSON(&s.Suite, path, "", &res)
+	s.Len(res.Broadcasts, 10)
+
+	// count above the limit is clamped
+	getJSON(&s.Suite, path+"?count=100000", "", &res)
+	s.Len(res.Broadcasts, 100)
+
+	getJSON(&s.Suite, path+"?count=100&page=2", "", &res)
+	s.Len(res.Broadcasts, 1)
+}
+
+type LivestreamViewersTestSuite struct {
+	suite.Suite
+}
+
+func TestLivestreamViewersSuite(t *testing.T) {
+	suite.Run(t, new(LivestreamViewersTestSuite))
+}
+
+// samples buffered by one instance are written by any other and served as a graph
+func (s *LivestreamViewersTestSuite) TestSamples() {
+	ctx := context.Background()
+
+	link := "viewers-samples-category"
+	err := app.CategoryRepo.Create(ctx, categoryDomain.CategoryCreate{Name: link, Link: link, Tags: []int{}})
+	s.Require().NoError(err)
+
+	cat, err := app.CategoryRepo.GetByLink(ctx, link)
+	s.Require().NoError(err)
+
+	_, id := signUp(&s.Suite, ts.URL+"/api", "viewers-samples-streamer")
+
+	_, err = pgpool.Exec(ctx, "UPDATE tc_user SET id_category = $1 WHERE id = $2", cat.Id, id)
+	s.Require().NoError(err)
+
+	ls, err := app.LivestreamRepo.Create(ctx, livestreamDomain.LivestreamCreate{Username: "viewers-samples-streamer"})
+	s.Require().NoError(err)
+	defer app.LivestreamRepo.Delete(ctx, ls.Id) // nolint
+
+	s.Require().NoError(app.LivestreamRepo.UpdateViewers(ctx, ls.Id, 10))
+	// samples are kept with second precision
+	time.Sleep(time.Second)
+	s.Require().NoError(app.LivestreamRepo.UpdateViewers(ctx, ls.Id, 30))
+
+	other := livestreamStorage.NewRepo(rclient, pgpool)
+	_, err = other.FlushViewers(ctx)
+	s.Require().NoError(err)
+
+	path := fmt.Sprintf("%s/api/livestreams/%d/viewers", ts.URL, ls.Id)
+
+	var res livestreamAPI.ViewersResponse
+	getJSON(&s.Suite, path+"?resolution=24h", "", &res)
+	s.Require().Len(res.Samples, 1)
+	s.Equal(30, res.Samples[0].PeakViewers)
+	s.Equal(20, res.Samples[0].AvgViewers)
+
+	resp := doRequest(&s.Suite, http.MethodGet, path+"?resolution=often", "", nil)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,49 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"twitchy-api/internal/external/mailer"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MailerTestSuite struct {
+	suite.Suite
+}
+
+func TestMailerSuite(t *testing.T) {
+	suite.Run(t, new(MailerTestSuite))
+}
+
+// a server that accepts connections and never greets doesn't hold the sender past the deadline
+func (s *MailerTestSuite) TestSendTimeout() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	s.Require().NoError(err)
+
+	ml := mailer.NewSMTP(host, port, "", "", "noreply@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = ml.Send(ctx, mailer.Message{To: "user@example.com", Subject: "subject", Body: "body"})
+	s.Error(err)
+	s.Less(time.Since(start), 2*time.Second)
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"log/slog"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 	application "twitchy-api/internal/app"
@@ -29,6 +31,8 @@ var (
 	app     *application.App
 )
 
+const webhookSecret = "test-webhook-secret"
+
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 	cfg := application.GetConfig()
@@ -69,6 +73,13 @@ func TestMain(m *testing.M) {
 		}
 	}()
 
+	// verification emails are read back from the file by tests
+	cfg.Mail.Driver = "log"
+	cfg.Mail.SinkFile = filepath.Join(os.TempDir(), fmt.Sprintf("twitchy-mail-%d.log", time.Now().UnixNano()))
+	defer os.Remove(cfg.Mail.SinkFile)
+
+	cfg.Webhook.Secret = webhookSecret
+
 	app, err = application.NewApp(logger,
 		rclient,
 		pgpool,
@@ -88,8 +99,9 @@ func TestMain(m *testing.M) {
 	// 	"http://127.0.0.1:1985/api/streams")
 
 	// app.Init(ctx, cfg.Update)
-	authMw := application.NewAuthMiddleware(logger, false)
-	handler := app.CreateHandler(authMw)
+	authMw := application.NewAuthMiddleware(logger, false, rclient, app.Keys, app.UserRepo)
+	optionalAuthMw := application.NewOptionalAuthMiddleware(logger, false, rclient, app.Keys, app.UserRepo)
+	handler := app.CreateHandler(authMw, optionalAuthMw)
 	ts = httptest.NewServer(handler)
 	defer ts.Close()
 
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"twitchy-api/internal/external/payment"
+	api "twitchy-api/pkg/api/subscription"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SubscriptionTestSuite struct {
+	suite.Suite
+	url string
+}
+
+func (s *SubscriptionTestSuite) SetupSuite() {
+	s.url = ts.URL + "/api"
+}
+
+func TestSubscriptionSuite(t *testing.T) {
+	suite.Run(t, new(SubscriptionTestSuite))
+}
+
+func (s *SubscriptionTestSuite) TestTiers() {
+	ownerToken, _ := signUp(&s.Suite, s.url, "tier-streamer")
+	viewerToken, _ := signUp(&s.Suite, s.url, "tier-viewer")
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-streamer/subscription/tiers", viewerToken, api.PostTierRequest{Name: "Tier 1", PriceCents: 499})
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-streamer/subscription/tiers", ownerToken, api.PostTierRequest{Name: "Tier 1", PriceCents: 0})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-streamer/subscription/tiers", ownerToken, api.PostTierRequest{Name: "Tier 1", PriceCents: 499})
+	s.Equal(http.StatusCreated, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-streamer/subscription/tiers", ownerToken, api.PostTierRequest{Name: "Tier 1", PriceCents: 999})
+	s.Equal(http.StatusConflict, resp.StatusCode)
+
+	var tiers api.ListTiersResponse
+	getJSON(&s.Suite, s.url+"/channels/tier-streamer/subscription/tiers", "", &tiers)
+	s.Require().Len(tiers.Tiers, 1)
+	s.Equal(499, tiers.Tiers[0].PriceCents)
+
+	resp = doRequest(&s.Suite, http.MethodDelete, fmt.Sprintf("%s/channels/tier-streamer/subscription/tiers/%d", s.url, tiers.Tiers[0].Id), ownerToken, nil)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+}
+
+func (s *SubscriptionTestSuite) TestSubscribeAndRenew() {
+	ctx := context.Background()
+
+	ownerToken, _ := signUp(&s.Suite, s.url, "sub-streamer")
+	viewerToken, viewerId := signUp(&s.Suite, s.url, "sub-viewer")
+
+	fake, ok := app.PaymentProvider.(*payment.Fake)
+	s.Require().True(ok, "payment provider is not fake")
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/channels/sub-streamer/subscription/tiers", ownerToken, api.PostTierRequest{Name: "Tier 1", PriceCents: 499})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	var tiers api.ListTiersResponse
+	getJSON(&s.Suite, s.url+"/channels/sub-streamer/subscription/tiers", "", &tiers)
+	s.Require().Len(tiers.Tiers, 1)
+	tierId := tiers.Tiers[0].Id
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/sub-streamer/subscription", ownerToken, api.PostRequest{TierId: tierId})
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/sub-streamer/subscription", viewerToken, api.PostRequest{TierId: tierId})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Len(fake.Charges(viewerId), 1)
+
+	// tiers with subscribers can't be deleted
+	resp = doRequest(&s.Suite, http.MethodDelete, fmt.Sprintf("%s/channels/sub-streamer/subscription/tiers/%d", s.url, tierId), ownerToken, nil)
+	s.Equal(http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(&s.Suite, http.MethodDelete, s.url+"/channels/sub-streamer/subscription", viewerToken, nil)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+
+	var sub api.GetResponse
+	getJSON(&s.Suite, s.url+"/channels/sub-streamer/subscription", viewerToken, &sub)
+	s.False(sub.AutoRenew)
+
+	// resubscribing while active resumes without charging
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/sub-streamer/subscription", viewerToken, api.PostRequest{TierId: tierId})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Len(fake.Charges(viewerId), 1)
+
+	s.expire(ctx, viewerId)
+	_, err := app.SubscriptionService.RenewDue(ctx, 100)
+	s.Require().NoError(err)
+
+	getJSON(&s.Suite, s.url+"/channels/sub-streamer/subscription", viewerToken, &sub)
+	s.Equal(2, sub.Months)
+	s.True(sub.PeriodEnd.After(time.Now()))
+	s.Len(fake.Charges(viewerId), 2)
+
+	// declined renewal ends the subscription
+	fake.Decline(viewerId, true)
+	defer fake.Decline(viewerId, false)
+
+	s.expire(ctx, viewerId)
+	_, err = app.SubscriptionService.RenewDue(ctx, 100)
+	s.Require().NoError(err)
+
+	resp = doRequest(&s.Suite, http.MethodGet, s.url+"/channels/sub-streamer/subscription", viewerToken, nil)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *SubscriptionTestSuite) TestChangeTier() {
+	ctx := context.Background()
+
+	ownerToken, _ := signUp(&s.Suite, s.url, "tier-change-streamer")
+	viewerToken, viewerId := signUp(&s.Suite, s.url, "tier-change-viewer")
+
+	fake, ok := app.PaymentProvider.(*payment.Fake)
+	s.Require().True(ok, "payment provider is not fake")
+
+	for _, tier := range []api.PostTierRequest{{Name: "Tier 1", PriceCents: 499}, {Name: "Tier 2", PriceCents: 999}} {
+		resp := doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-change-streamer/subscription/tiers", ownerToken, tier)
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+	}
+
+	var tiers api.ListTiersResponse
+	getJSON(&s.Suite, s.url+"/channels/tier-change-streamer/subscription/tiers", "", &tiers)
+	s.Require().Len(tiers.Tiers, 2)
+	cheap, pricey := tiers.Tiers[0].Id, tiers.Tiers[1].Id
+
+	resp := doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-change-streamer/subscription", viewerToken, api.PostRequest{TierId: cheap})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	// upgrades are charged the difference and apply at once
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-change-streamer/subscription", viewerToken, api.PostRequest{TierId: pricey})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	charges := fake.Charges(viewerId)
+	s.Require().Len(charges, 2)
+	s.Equal(500, charges[1].AmountCents)
+
+	var sub api.GetResponse
+	getJSON(&s.Suite, s.url+"/channels/tier-change-streamer/subscription", viewerToken, &sub)
+	s.Equal(pricey, sub.TierId)
+
+	// downgrades wait for the renewal
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-change-streamer/subscription", viewerToken, api.PostRequest{TierId: cheap})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Len(fake.Charges(viewerId), 2)
+
+	getJSON(&s.Suite, s.url+"/channels/tier-change-streamer/subscription", viewerToken, &sub)
+	s.Equal(pricey, sub.TierId)
+	s.Equal(cheap, sub.NextTierId)
+
+	// a due subscription stays active until it is renewed, subscribing again doesn't charge
+	s.expire(ctx, viewerId)
+	resp = doRequest(&s.Suite, http.MethodPost, s.url+"/channels/tier-change-streamer/subscription", viewerToken, api.PostRequest{TierId: cheap})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Len(fake.Charges(viewerId), 2)
+
+	_, err := app.SubscriptionService.RenewDue(ctx, 100)
+	s.Require().NoError(err)
+
+	charges = fake.Charges(viewerId)
+	s.Require().Len(charges, 3)
+	s.Equal(499, charges[2].AmountCents)
+
+	getJSON(&s.Suite, s.url+"/channels/tier-change-streamer/subscription", viewerToken, &sub)
+	s.Equal(cheap, sub.TierId)
+	s.Zero(sub.NextTierId)
+	s.Equal(2, sub.Months)
+}
+
+// moves the end of the paid period of every subscription of the user to the past
+func (s *SubscriptionTestSuite) expire(ctx context.Context, userId int32) {
+	_, err := pgpool.Exec(ctx, "UPDATE tc_user_subscriber SET period_end = now() - interval '1 minute' WHERE id_user = $1", userId)
+	s.Require().NoError(err)
+}